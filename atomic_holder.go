@@ -0,0 +1,103 @@
+package sbc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// AtomicHolder is a container that holds a value of any type, like Holder, but readers never take a lock:
+// the value is stored in an atomic.Pointer and replaced as a whole on every change. The waiting readers
+// are notified by closing the channel of the replaced state.
+type AtomicHolder[T any] struct {
+	// mu serializes the writers, the readers only load the state
+	mu    sync.Mutex
+	state atomic.Pointer[holderState[T]]
+}
+
+// holderState is an immutable state of an AtomicHolder.
+type holderState[T any] struct {
+	value   T
+	version Version
+	seq     uint64
+	// changed is closed when the value changes
+	changed chan struct{}
+}
+
+// NewAtomicHolder creates a new AtomicHolder with the given value
+func NewAtomicHolder[T any](value T) *AtomicHolder[T] {
+	h := &AtomicHolder[T]{}
+	h.state.Store(&holderState[T]{value: value, changed: make(chan struct{})})
+	return h
+}
+
+// GetValue returns the value of the holder
+func (h *AtomicHolder[T]) GetValue() T {
+	return h.state.Load().value
+}
+
+// Updates returns a receive-only channel that sends updates of type T.
+// The channel is closed when the context is done.
+func (h *AtomicHolder[T]) Updates(ctx context.Context) <-chan T {
+	return updatesOf[T](ctx, h, nil)
+}
+
+// getValueVersion returns the value of the holder together with its version
+func (h *AtomicHolder[T]) getValueVersion() (T, Version) {
+	st := h.state.Load()
+	return st.value, st.version
+}
+
+// setValue sets the value of the holder
+func (h *AtomicHolder[T]) setValue(value T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.replace(value, h.state.Load().version)
+}
+
+// setValueVersion sets the value of the holder together with its version
+func (h *AtomicHolder[T]) setValueVersion(value T, version Version) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.replace(value, version)
+}
+
+// setVersion sets the version of the holder without notifying the waiting goroutines,
+// it is used when an update does not change the value
+func (h *AtomicHolder[T]) setVersion(version Version) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := *h.state.Load()
+	st.version = version
+	h.state.Store(&st)
+}
+
+// replace stores the new state and notifies the waiting goroutines, the lock must be held.
+func (h *AtomicHolder[T]) replace(value T, version Version) {
+	old := h.state.Load()
+	h.state.Store(&holderState[T]{value: value, version: version, seq: old.seq + 1, changed: make(chan struct{})})
+	close(old.changed)
+}
+
+// current returns the value of the holder together with its sequence number
+func (h *AtomicHolder[T]) current() (T, uint64) {
+	st := h.state.Load()
+	return st.value, st.seq
+}
+
+// next waits until the value changes after the given sequence number and returns the latest value
+// with its sequence number. It returns false when the context is done first.
+func (h *AtomicHolder[T]) next(ctx context.Context, seq uint64) (T, uint64, bool) {
+	for {
+		st := h.state.Load()
+		if st.seq != seq {
+			return st.value, st.seq, true
+		}
+		select {
+		case <-st.changed:
+		case <-ctx.Done():
+			var defT T
+			return defT, seq, false
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package sbc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAtomicHolderSetValue(t *testing.T) {
+	holder := NewAtomicHolder[int](10)
+	if holder.GetValue() != 10 {
+		t.Fail()
+	}
+	holder.setValue(20)
+	if holder.GetValue() != 20 {
+		t.Fail()
+	}
+}
+
+func TestAtomicHolderUpdates(t *testing.T) {
+	holder := NewAtomicHolder[int](10)
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := holder.Updates(ctx)
+	// the version alone is not a change
+	holder.setVersion(Version{Revision: 1})
+	holder.setValue(20)
+	select {
+	case val := <-updates:
+		if val != 20 {
+			t.Errorf("Expected 20, got %d", val)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no update received")
+	}
+	if _, version := holder.getValueVersion(); version.Revision != 1 {
+		t.Errorf("unexpected version %+v", version)
+	}
+	cancel()
+	if _, ok := <-updates; ok {
+		t.Error("Expected the channel to be closed")
+	}
+}
+
+func TestSubscriptionWithAtomicHolder(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithAtomicHolder())
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	if _, ok := confSubs.holder.(*AtomicHolder[testConfig]); !ok {
+		t.Fatalf("unexpected holder %T", confSubs.holder)
+	}
+	transport.put("config", `{"value": 2}`)
+	waitFor(t, func() bool { return confSubs.Get().Value == 2 && confSubs.Version().Revision == 2 })
+}
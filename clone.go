@@ -0,0 +1,125 @@
+package sbc
+
+import "reflect"
+
+// cloner is implemented by the types that know how to deep copy themselves.
+type cloner[T any] interface {
+	Clone() T
+}
+
+// cloneValue returns a deep copy of the value. It uses the Clone method of T if there is one,
+// otherwise it copies the value with reflection, see deepCopy.
+func cloneValue[T any](v T) T {
+	if c, ok := any(v).(cloner[T]); ok {
+		return c.Clone()
+	}
+	// the copy is set into a T rather than asserted to T, which panics for a nil interface
+	var dst T
+	reflect.ValueOf(&dst).Elem().Set(deepCopy(reflect.ValueOf(&v).Elem(), map[visit]reflect.Value{}))
+	return dst
+}
+
+// visit is a pointer that was already copied, it makes cyclic values copyable.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// cloneMethod returns the Clone method of the value if it has one that returns the same type.
+func cloneMethod(v reflect.Value) (reflect.Value, bool) {
+	if !v.CanInterface() {
+		return reflect.Value{}, false
+	}
+	m := v.MethodByName("Clone")
+	if !m.IsValid() {
+		return reflect.Value{}, false
+	}
+	mt := m.Type()
+	if mt.NumIn() != 0 || mt.NumOut() != 1 || mt.Out(0) != v.Type() {
+		return reflect.Value{}, false
+	}
+	return m, true
+}
+
+// deepCopy returns a deep copy of the value: pointers, maps, slices, arrays, interfaces and the exported
+// fields of structs are copied recursively, a value with a Clone method returning its own type is copied
+// with it. Unexported fields, channels and functions are copied shallowly.
+func deepCopy(src reflect.Value, seen map[visit]reflect.Value) reflect.Value {
+	if !src.IsValid() {
+		return src
+	}
+	// the Clone method of a nil pointer or interface is not called, the copy is nil as well
+	if !isNilValue(src) {
+		if m, ok := cloneMethod(src); ok {
+			return m.Call(nil)[0]
+		}
+	}
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		key := visit{ptr: src.Pointer(), typ: src.Type()}
+		if dst, ok := seen[key]; ok {
+			return dst
+		}
+		dst := reflect.New(src.Type().Elem())
+		seen[key] = dst
+		dst.Elem().Set(deepCopy(src.Elem(), seen))
+		return dst
+	case reflect.Interface:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(deepCopy(src.Elem(), seen))
+		return dst
+	case reflect.Map:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		dst := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(iter.Key(), deepCopy(iter.Value(), seen))
+		}
+		return dst
+	case reflect.Slice:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		dst := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := range src.Len() {
+			dst.Index(i).Set(deepCopy(src.Index(i), seen))
+		}
+		return dst
+	case reflect.Array:
+		dst := reflect.New(src.Type()).Elem()
+		for i := range src.Len() {
+			dst.Index(i).Set(deepCopy(src.Index(i), seen))
+		}
+		return dst
+	case reflect.Struct:
+		dst := reflect.New(src.Type()).Elem()
+		// copy all the fields, including the unexported ones, then replace the exported ones with deep copies
+		dst.Set(src)
+		for i := range src.NumField() {
+			if dst.Field(i).CanSet() {
+				dst.Field(i).Set(deepCopy(src.Field(i), seen))
+			}
+		}
+		return dst
+	default:
+		return src
+	}
+}
+
+// isNilValue reports whether the value is a nil pointer or interface.
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,147 @@
+package sbc
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+)
+
+type cloneNode struct {
+	Name string
+	Next *cloneNode
+}
+
+type clonedConfig struct {
+	Hosts   []string          `json:"hosts"`
+	Labels  map[string]string `json:"labels"`
+	Primary *cloneNode        `json:"primary"`
+	Extra   any               `json:"extra"`
+	Pool    [2][]int          `json:"pool"`
+	hidden  []int
+}
+
+func TestCloneValue(t *testing.T) {
+	node := &cloneNode{Name: "a"}
+	node.Next = node
+	src := clonedConfig{
+		Hosts:   []string{"a", "b"},
+		Labels:  map[string]string{"env": "prod"},
+		Primary: node,
+		Extra:   []int{1},
+		Pool:    [2][]int{{1}, {2}},
+		hidden:  []int{1},
+	}
+	dst := cloneValue(src)
+	dst.Hosts[0] = "changed"
+	dst.Labels["env"] = "changed"
+	dst.Primary.Name = "changed"
+	dst.Extra.([]int)[0] = 2
+	dst.Pool[0][0] = 2
+	if src.Hosts[0] != "a" || src.Labels["env"] != "prod" || src.Primary.Name != "a" || src.Extra.([]int)[0] != 1 || src.Pool[0][0] != 1 {
+		t.Errorf("the source is modified: %+v", src)
+	}
+	// the cycle is copied as a cycle
+	if dst.Primary.Next != dst.Primary {
+		t.Error("Expected a cyclic copy")
+	}
+	// unexported fields are shared
+	if &dst.hidden[0] != &src.hidden[0] {
+		t.Error("Expected a shared unexported field")
+	}
+}
+
+// clonerConfig counts the calls of its Clone method.
+type clonerConfig struct {
+	Hosts  []string
+	clones *int
+}
+
+func (c clonerConfig) Clone() clonerConfig {
+	*c.clones++
+	return clonerConfig{Hosts: slices.Clone(c.Hosts), clones: c.clones}
+}
+
+func TestCloneValueUsesCloneMethod(t *testing.T) {
+	clones := 0
+	src := clonerConfig{Hosts: []string{"a"}, clones: &clones}
+	cloneValue(src)
+	// the nested value is copied with its Clone method too
+	cloneValue(struct{ Nested clonerConfig }{src})
+	if clones != 2 {
+		t.Errorf("Expected 2 clones, got %d", clones)
+	}
+}
+
+// clonerIface is an interface with a Clone method returning the interface type.
+type clonerIface interface {
+	Clone() clonerIface
+}
+
+func TestCloneValueNil(t *testing.T) {
+	if v := cloneValue[any](nil); v != nil {
+		t.Errorf("Expected nil, got %v", v)
+	}
+	if v := cloneValue[error](nil); v != nil {
+		t.Errorf("Expected nil, got %v", v)
+	}
+	if v := cloneValue[clonerIface](nil); v != nil {
+		t.Errorf("Expected nil, got %v", v)
+	}
+	if v := cloneValue[*cloneNode](nil); v != nil {
+		t.Errorf("Expected nil, got %v", v)
+	}
+	if v := cloneValue[map[string]int](nil); v != nil {
+		t.Errorf("Expected nil, got %v", v)
+	}
+	v := cloneValue(struct {
+		Iface  clonerIface
+		Values []any
+	}{Values: []any{nil, 1}})
+	if v.Iface != nil || len(v.Values) != 2 || v.Values[0] != nil || v.Values[1] != 1 {
+		t.Errorf("unexpected copy %+v", v)
+	}
+}
+
+func TestCloneValueInterface(t *testing.T) {
+	src := any(map[string][]int{"a": {1}})
+	dst := cloneValue(src)
+	dst.(map[string][]int)["a"][0] = 2
+	if src.(map[string][]int)["a"][0] != 1 {
+		t.Errorf("the source is modified: %v", src)
+	}
+}
+
+func TestSubscriptionImmutableValues(t *testing.T) {
+	data, _ := json.Marshal(clonedConfig{Hosts: []string{"a"}, Labels: map[string]string{"env": "prod"}})
+	transport := newMockTransport(map[string]string{"config": string(data)})
+	sub := NewSubscriber[clonedConfig](transport, KeyBuilderFunc[clonedConfig](func(clonedConfig) string { return "config" }),
+		WithImmutableValues())
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	// the readers modify their copies concurrently, the race detector reports any shared memory
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				conf := confSubs.Get()
+				conf.Hosts = append(conf.Hosts[:0], "changed")
+				conf.Labels["env"] = "changed"
+				for conf := range confSubs.All(context.Background()) {
+					conf.Hosts[0] = "changed"
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if conf := confSubs.Get(); conf.Hosts[0] != "a" || conf.Labels["env"] != "prod" {
+		t.Errorf("the value is modified: %+v", conf)
+	}
+}
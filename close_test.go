@@ -0,0 +1,175 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tickingTransport is a legacy Transport whose update goroutine sends without watching the context,
+// like a naive transport, and stops only when it notices the context is done after a send.
+type tickingTransport struct {
+	stopped atomic.Bool
+	closed  atomic.Bool
+}
+
+func (tt *tickingTransport) Current(context.Context, string) ([]byte, error) {
+	return []byte(`{"value": 1}`), nil
+}
+
+func (tt *tickingTransport) Updates(ctx context.Context, _ string) (<-chan []byte, error) {
+	ch := make(chan []byte)
+	go func() {
+		defer tt.stopped.Store(true)
+		defer close(ch)
+		for i := 2; ctx.Err() == nil; i++ {
+			ch <- []byte(`{"value": ` + strconv.Itoa(i) + `}`)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	return ch, nil
+}
+
+func (tt *tickingTransport) Close(context.Context) error {
+	tt.closed.Store(true)
+	return nil
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	transport := &tickingTransport{}
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := confSubs.GetUpdates()
+	handled := make(chan struct{})
+	var once sync.Once
+	var running atomic.Int32
+	confSubs.OnChange(func(testConfig, testConfig) error {
+		running.Add(1)
+		defer running.Add(-1)
+		once.Do(func() { close(handled) })
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}, WithHandlerTimeout(time.Millisecond))
+	// the handlers time out and keep running on their own goroutines
+	<-handled
+	if err := confSubs.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if running.Load() != 0 {
+		t.Error("Expected Close to wait for the handlers")
+	}
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Error("unexpected update")
+		}
+	default:
+		t.Error("Expected the updates to be closed")
+	}
+	// the transport goroutine is drained until it stops, it sets the flag right after closing the channel
+	waitFor(t, transport.stopped.Load)
+	if err := sub.Close(context.Background()); err != nil || !transport.closed.Load() {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSubscriptionCloseTimeout(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	confSubs := subscribeTest(t, transport, "config")
+	release := make(chan struct{})
+	defer close(release)
+	confSubs.OnChange(func(testConfig, testConfig) error {
+		<-release
+		return nil
+	}, WithHandlerTimeout(time.Millisecond))
+	transport.put("config", `{"value": 2}`)
+	waitFor(t, func() bool { return confSubs.Get().Value == 2 })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := confSubs.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// closingTransport is a transport whose updates end right away.
+type closingTransport struct {
+	legacyTransport
+}
+
+func (closingTransport) CurrentUpdate(_ context.Context, key string) (Update, error) {
+	return Update{Key: key, Value: []byte(`{"value": 1}`)}, nil
+}
+
+func (closingTransport) WatchUpdates(context.Context, string) (<-chan Update, error) {
+	ch := make(chan Update)
+	close(ch)
+	return ch, nil
+}
+
+func TestSubscriptionCloseTerminalError(t *testing.T) {
+	sub := NewSubscriber[testConfig](closingTransport{}, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return confSubs.Err() != nil })
+	if err := confSubs.Close(context.Background()); !errors.Is(err, ErrUpdatesClosed) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSubscriptionCloseNotReady(t *testing.T) {
+	confSubs, err := newTestSubscriber(newMockTransport(nil), "config").SubscribeAsync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return confSubs.loadErr.Load() != nil })
+	if err := confSubs.Close(context.Background()); !errors.Is(err, ErrNotReady) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSubscriptionGetUpdatesAfterClose(t *testing.T) {
+	confSubs := subscribeTest(t, newMockTransport(map[string]string{"config": `{"value": 1}`}), "config")
+	if err := confSubs.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-confSubs.GetUpdates(); ok {
+		t.Error("unexpected update")
+	}
+}
+
+func TestGoroutinesClosing(t *testing.T) {
+	var g goroutines
+	if err := g.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if g.Go(func() {}) {
+		t.Error("Expected Go to refuse the goroutine after wait")
+	}
+}
+
+func TestMapSubscriptionClose(t *testing.T) {
+	transport := newMockTransport(map[string]string{"tenants/1": `{"value": 1}`})
+	sub, err := newTestSubscriber(transport, "").SubscribePrefix(context.Background(), "tenants/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := sub.GetUpdates()
+	if err := sub.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-updates; ok {
+		t.Error("unexpected update")
+	}
+}
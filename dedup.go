@@ -0,0 +1,48 @@
+package sbc
+
+import "reflect"
+
+// Dedup defines how a subscription detects updates that do not change the value. Such updates are not
+// sent to the GetUpdates consumers, only the Version and LastUpdate of the subscription move on.
+type Dedup int
+
+const (
+	// DedupNone applies every update received from the transport.
+	DedupNone Dedup = iota
+
+	// DedupBytes skips updates whose raw value has the same hash as the current one. It is the default.
+	DedupBytes
+
+	// DedupSemantic skips updates whose raw value is the same, and updates whose decoded value is equal to
+	// the current one, e.g. the same JSON with another field order or formatting. The values are compared
+	// with their Equal method if T has one, otherwise with reflect.DeepEqual.
+	DedupSemantic
+)
+
+// equaler is implemented by the types that define their own equality, like time.Time.
+type equaler[T any] interface {
+	Equal(T) bool
+}
+
+// isNoop reports whether the update with the given value and version does not change the current value.
+func isNoop[T any](d Dedup, cur T, curVersion Version, val T, version Version) bool {
+	switch d {
+	case DedupBytes:
+		return curVersion.Hash == version.Hash
+	case DedupSemantic:
+		return curVersion.Hash == version.Hash || equal(cur, val)
+	default:
+		return false
+	}
+}
+
+// equal reports whether the values are equal, using their Equal method if T has one.
+func equal[T any](a, b T) bool {
+	if e, ok := any(a).(equaler[T]); ok {
+		return e.Equal(b)
+	}
+	if e, ok := any(&a).(equaler[T]); ok {
+		return e.Equal(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
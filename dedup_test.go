@@ -0,0 +1,57 @@
+package sbc
+
+import (
+	"testing"
+	"time"
+)
+
+// equalConfig is equal to another equalConfig with the same name in any case.
+type equalConfig struct {
+	Name string
+}
+
+func (c equalConfig) Equal(other equalConfig) bool {
+	return len(c.Name) == len(other.Name)
+}
+
+func TestIsNoop(t *testing.T) {
+	v1 := newVersion(Update{Value: []byte(`{"value": 1}`), Revision: 1})
+	v2 := newVersion(Update{Value: []byte(`{"value": 1}`), Revision: 2})
+	v3 := newVersion(Update{Value: []byte(`{"value":1}`), Revision: 3})
+	one := testConfig{Value: 1}
+	tests := []struct {
+		name     string
+		dedup    Dedup
+		version  Version
+		expected bool
+	}{
+		{"none same bytes", DedupNone, v2, false},
+		{"bytes same bytes", DedupBytes, v2, true},
+		{"bytes other bytes", DedupBytes, v3, false},
+		{"semantic same bytes", DedupSemantic, v2, true},
+		{"semantic equal value", DedupSemantic, v3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNoop(tt.dedup, one, v1, one, tt.version); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+	if isNoop(DedupSemantic, one, v1, testConfig{Value: 2}, v3) {
+		t.Error("Expected a changed value")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	if !equal(equalConfig{Name: "foo"}, equalConfig{Name: "bar"}) {
+		t.Error("Expected the Equal method to be used")
+	}
+	now := time.Now()
+	if !equal(now, now.In(time.FixedZone("test", 3600))) {
+		t.Error("Expected equal times in different zones")
+	}
+	if equal(map[string]int{"a": 1}, map[string]int{"a": 2}) {
+		t.Error("Expected different maps")
+	}
+}
@@ -0,0 +1,243 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrSourceClosed is an error that is returned when a source of a derived value has no value anymore,
+	// e.g. its subscription is unsubscribed.
+	ErrSourceClosed = errors.New("source closed")
+
+	// ErrFilteredOut is an error that is returned by Filter when the current value of the source does not
+	// pass the filter.
+	ErrFilteredOut = errors.New("value filtered out")
+)
+
+// Source is a value that changes over time, like a Subscription or a Derived value.
+type Source[T any] interface {
+
+	// Get returns the current value.
+	Get() T
+
+	// All returns an iterator over the current value and then every new value.
+	All(ctx context.Context) iter.Seq[T]
+}
+
+var (
+	_ Source[any] = (*Subscription[any])(nil)
+	_ Source[any] = (*Derived[any])(nil)
+)
+
+// Derived is a read-only value computed from one or several sources, see Map, Filter, Combine2 and Combine3.
+// It is recomputed only when a source changes. When the computation fails, the last value is kept and
+// the error is reported by Err. A Derived value stops following its sources when any of them is closed,
+// e.g. unsubscribed, or when Stop or Close is called.
+type Derived[T any] struct {
+	holder *AtomicHolder[T]
+	err    atomic.Pointer[error]
+	// mu guards the values of the sources while the value is recomputed
+	mu      sync.Mutex
+	compute func() (T, bool, error)
+
+	ctx        context.Context
+	cancel     context.CancelFunc
+	goroutines goroutines
+}
+
+// Map returns a Derived value that is the result of fn applied to the value of the source.
+// It fails when fn fails for the current value of the source.
+//
+//	limits, err := sbc.Map(sub, func(c Config) (RateLimits, error) { return c.RateLimits, nil })
+func Map[T, U any](src Source[T], fn func(T) (U, error)) (*Derived[U], error) {
+	var t T
+	return newDerived(func() (U, bool, error) {
+		u, err := fn(t)
+		return u, true, err
+	}, inputOf(src, &t))
+}
+
+// Filter returns a Derived value that follows the value of the source when it passes the filter,
+// and keeps the last value that passed it otherwise. Like all derived values, it sees the latest value
+// of the source, the intermediate values of quick successive changes may be skipped. It fails with ErrFilteredOut when the current value
+// of the source does not pass the filter.
+func Filter[T any](src Source[T], keep func(T) bool) (*Derived[T], error) {
+	var t T
+	return newDerived(func() (T, bool, error) {
+		return t, keep(t), nil
+	}, inputOf(src, &t))
+}
+
+// Combine2 returns a Derived value that is the result of fn applied to the values of both sources,
+// recomputed when either of them changes. It fails when fn fails for the current values of the sources.
+func Combine2[A, B, U any](a Source[A], b Source[B], fn func(A, B) (U, error)) (*Derived[U], error) {
+	var va A
+	var vb B
+	return newDerived(func() (U, bool, error) {
+		u, err := fn(va, vb)
+		return u, true, err
+	}, inputOf(a, &va), inputOf(b, &vb))
+}
+
+// Combine3 returns a Derived value that is the result of fn applied to the values of the three sources,
+// recomputed when any of them changes. It fails when fn fails for the current values of the sources.
+func Combine3[A, B, C, U any](a Source[A], b Source[B], c Source[C], fn func(A, B, C) (U, error)) (*Derived[U], error) {
+	var va A
+	var vb B
+	var vc C
+	return newDerived(func() (U, bool, error) {
+		u, err := fn(va, vb, vc)
+		return u, true, err
+	}, inputOf(a, &va), inputOf(b, &vb), inputOf(c, &vc))
+}
+
+// Get returns the current value.
+func (d *Derived[T]) Get() T {
+	return d.holder.GetValue()
+}
+
+// Err returns the error of the last computation, or nil if it succeeded.
+func (d *Derived[T]) Err() error {
+	if err := d.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// GetUpdates returns a receive-only channel that sends the new values.
+// The channel is closed when the derived value stops.
+func (d *Derived[T]) GetUpdates() <-chan T {
+	return updatesOf[T](d.ctx, d.holder, d.goroutines.Go)
+}
+
+// All returns an iterator over the current value and then every new value, see Subscription.All.
+func (d *Derived[T]) All(ctx context.Context) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ctx, cancel := mergeContext(ctx, d.ctx)
+		defer cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		val, seq := d.holder.current()
+		if !yield(val) {
+			return
+		}
+		for val := range valuesAfter[T](ctx, d.holder, seq) {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// Stop stops following the sources, the value does not change anymore.
+// It does not wait for the goroutines of the derived value to exit, see Close.
+func (d *Derived[T]) Stop() {
+	d.cancel()
+}
+
+// Close stops following the sources like Stop, and waits until all the goroutines of the derived value exit:
+// the goroutines following the sources and the GetUpdates goroutines. It returns the context error
+// if the goroutines do not exit before the context is done.
+func (d *Derived[T]) Close(ctx context.Context) error {
+	d.cancel()
+	if err := d.goroutines.wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait for the derived goroutines: %w", err)
+	}
+	return nil
+}
+
+// input is a source of a derived value.
+type input func(ctx context.Context) (next func() (store func(), ok bool), stop func(), err error)
+
+// inputOf returns the input that stores the values of the source in val.
+func inputOf[T any](src Source[T], val *T) input {
+	return func(ctx context.Context) (func() (func(), bool), func(), error) {
+		next, stop := iter.Pull(src.All(ctx))
+		v, ok := next()
+		if !ok {
+			stop()
+			return nil, nil, ErrSourceClosed
+		}
+		*val = v
+		return func() (func(), bool) {
+			v, ok := next()
+			return func() { *val = v }, ok
+		}, stop, nil
+	}
+}
+
+// newDerived creates a Derived value computed from the inputs, and starts following them.
+// The compute function returns false when the value must not change.
+func newDerived[T any](compute func() (T, bool, error), inputs ...input) (*Derived[T], error) {
+	d := &Derived[T]{compute: compute}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	nexts := make([]func() (func(), bool), 0, len(inputs))
+	stops := make([]func(), 0, len(inputs))
+	stopAll := func() {
+		d.cancel()
+		for _, stop := range stops {
+			stop()
+		}
+	}
+	for i, in := range inputs {
+		next, stop, err := in(d.ctx)
+		if err != nil {
+			stopAll()
+			return nil, fmt.Errorf("failed to get the value of source %d: %w", i, err)
+		}
+		nexts = append(nexts, next)
+		stops = append(stops, stop)
+	}
+	val, ok, err := compute()
+	if err == nil && !ok {
+		err = ErrFilteredOut
+	}
+	if err != nil {
+		stopAll()
+		return nil, fmt.Errorf("failed to compute the initial value: %w", err)
+	}
+	d.holder = NewAtomicHolder(val)
+	for i := range nexts {
+		next, stop := nexts[i], stops[i]
+		if !d.goroutines.Go(func() { d.follow(next, stop) }) {
+			stop()
+		}
+	}
+	return d, nil
+}
+
+// follow recomputes the value on every change of the input, until the input or the derived value stops.
+func (d *Derived[T]) follow(next func() (func(), bool), stop func()) {
+	// a closed input stops the others too
+	defer d.cancel()
+	defer stop()
+	for {
+		store, ok := next()
+		if !ok {
+			return
+		}
+		d.recompute(store)
+	}
+}
+
+// recompute stores the new value of an input and recomputes the value.
+func (d *Derived[T]) recompute(store func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	store()
+	val, ok, err := d.compute()
+	if err != nil {
+		d.err.Store(&err)
+		return
+	}
+	d.err.Store(nil)
+	if ok {
+		d.holder.setValue(val)
+	}
+}
@@ -0,0 +1,164 @@
+package sbc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// subscribeTest subscribes to the key of the transport.
+func subscribeTest(t *testing.T, transport Transport, key string) *Subscription[testConfig] {
+	t.Helper()
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return key }))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(confSubs.Unsubscribe)
+	return confSubs
+}
+
+func TestMap(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	confSubs := subscribeTest(t, transport, "config")
+	calls := 0
+	str, err := Map(confSubs, func(c testConfig) (string, error) {
+		calls++
+		if c.Value < 0 {
+			return "", errors.New("negative value")
+		}
+		return strconv.Itoa(c.Value), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer str.Stop()
+	if str.Get() != "1" || calls != 1 {
+		t.Fatalf("unexpected value %s after %d calls", str.Get(), calls)
+	}
+	transport.put("config", `{"value": 2}`)
+	waitFor(t, func() bool { return str.Get() == "2" })
+	// a failed computation keeps the last value
+	transport.put("config", `{"value": -1}`)
+	waitFor(t, func() bool { return str.Err() != nil })
+	if str.Get() != "2" {
+		t.Errorf("Expected 2, got %s", str.Get())
+	}
+	transport.put("config", `{"value": 3}`)
+	waitFor(t, func() bool { return str.Get() == "3" && str.Err() == nil })
+}
+
+func TestMapInitialError(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	confSubs := subscribeTest(t, transport, "config")
+	fnErr := errors.New("failed")
+	if _, err := Map(confSubs, func(testConfig) (int, error) { return 0, fnErr }); !errors.Is(err, fnErr) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	confSubs := subscribeTest(t, transport, "config")
+	positive, err := Filter(confSubs, func(c testConfig) bool { return c.Value > 0 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer positive.Stop()
+	transport.put("config", `{"value": 2}`)
+	waitFor(t, func() bool { return positive.Get().Value == 2 })
+	transport.put("config", `{"value": -2}`)
+	waitFor(t, func() bool { return confSubs.Get().Value == -2 })
+	// the derived value is recomputed asynchronously, give it time
+	time.Sleep(10 * time.Millisecond)
+	if positive.Get().Value != 2 {
+		t.Errorf("Expected 2, got %d", positive.Get().Value)
+	}
+	if _, err := Filter(confSubs, func(c testConfig) bool { return c.Value > 0 }); !errors.Is(err, ErrFilteredOut) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	transport := newMockTransport(map[string]string{"a": `{"value": 1}`, "b": `{"value": 2}`, "c": `{"value": 3}`})
+	a, b, c := subscribeTest(t, transport, "a"), subscribeTest(t, transport, "b"), subscribeTest(t, transport, "c")
+	sum, err := Combine2(a, b, func(a, b testConfig) (int, error) { return a.Value + b.Value, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sum.Stop()
+	// derived values are sources too
+	total, err := Combine3(sum, c, a, func(sum int, c, a testConfig) (int, error) { return sum + c.Value + a.Value, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer total.Stop()
+	if sum.Get() != 3 || total.Get() != 7 {
+		t.Fatalf("unexpected values %d and %d", sum.Get(), total.Get())
+	}
+	transport.put("b", `{"value": 20}`)
+	waitFor(t, func() bool { return sum.Get() == 21 && total.Get() == 25 })
+	transport.put("a", `{"value": 10}`)
+	waitFor(t, func() bool { return sum.Get() == 30 && total.Get() == 43 })
+}
+
+func TestDerivedStopsWithSource(t *testing.T) {
+	transport := newMockTransport(map[string]string{"a": `{"value": 1}`, "b": `{"value": 2}`})
+	a, b := subscribeTest(t, transport, "a"), subscribeTest(t, transport, "b")
+	sum, err := Combine2(a, b, func(a, b testConfig) (int, error) { return a.Value + b.Value, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := sum.GetUpdates()
+	a.Unsubscribe()
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Error("unexpected update")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the derived value did not stop")
+	}
+	if _, err := Map(a, func(c testConfig) (int, error) { return c.Value, nil }); !errors.Is(err, ErrSourceClosed) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDerivedClose(t *testing.T) {
+	transport := newMockTransport(map[string]string{"a": `{"value": 1}`, "b": `{"value": 2}`})
+	a, b := subscribeTest(t, transport, "a"), subscribeTest(t, transport, "b")
+	sum, err := Combine2(a, b, func(a, b testConfig) (int, error) { return a.Value + b.Value, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := sum.GetUpdates()
+	if err := sum.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Close waited for the goroutines, so the channel is already closed
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Error("unexpected update")
+		}
+	default:
+		t.Error("Expected the updates to be closed")
+	}
+	buf := make([]byte, 1<<20)
+	if stacks := buf[:runtime.Stack(buf, true)]; bytes.Contains(stacks, []byte(").follow(")) {
+		t.Errorf("derived goroutines left running:\n%s", stacks)
+	}
+	if _, ok := <-sum.GetUpdates(); ok {
+		t.Error("Expected GetUpdates of a closed derived value to be closed")
+	}
+	// the sources are still running
+	transport.put("a", `{"value": 10}`)
+	waitFor(t, func() bool { return a.Get().Value == 10 })
+	if sum.Get() != 3 {
+		t.Errorf("Expected 3, got %d", sum.Get())
+	}
+}
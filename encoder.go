@@ -1,5 +1,7 @@
 package sbc
 
+import "fmt"
+
 // Encoder encodes the given value v into a byte slice.
 // Returns the encoded data as a byte slice and an error if encoding fails.
 type Encoder interface {
@@ -11,3 +13,48 @@ type Encoder interface {
 	// Returns an error if the decoding process fails.
 	Decode(data []byte, v any) error
 }
+
+// HeaderDecoder is an optional interface of an Encoder that uses the transport headers of an update,
+// like a content type or Consul flags, as hints for decoding. The subscription calls DecodeWithHeaders
+// instead of Decode when the encoder implements it, see sbcencoder.MuxEncoder.
+type HeaderDecoder interface {
+
+	// DecodeWithHeaders decodes the provided byte slice into the target variable v using the headers as hints.
+	DecodeWithHeaders(data []byte, headers map[string]string, v any) error
+}
+
+// DecodeError is an error that is returned when the value of an update can not be decoded, e.g. it is rejected
+// by a schema or a signature check of the encoder.
+type DecodeError struct {
+
+	// Version is the version of the update that was not decoded.
+	Version Version
+
+	// Err is the error returned by the encoder.
+	Err error
+}
+
+// Error returns the error message.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("value %s not decoded: %v", e.Version, e.Err)
+}
+
+// Unwrap returns the error returned by the encoder.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeUpdate decodes the value of the update into type T, passing the headers to a HeaderDecoder.
+func decodeUpdate[T any](encoder Encoder, upd Update) (T, error) {
+	var t T
+	if hd, ok := encoder.(HeaderDecoder); ok {
+		if err := hd.DecodeWithHeaders(upd.Value, upd.Headers, &t); err != nil {
+			return t, err
+		}
+		return t, nil
+	}
+	if err := encoder.Decode(upd.Value, &t); err != nil {
+		return t, err
+	}
+	return t, nil
+}
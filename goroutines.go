@@ -0,0 +1,52 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosing is an error that is returned when a subscription that is closing is asked to start a goroutine,
+// like the one of an OnChange handler with a timeout, see Subscription.Close.
+var ErrClosing = errors.New("subscription is closing")
+
+// goroutines is a group of goroutines that can be waited for, like the goroutines of a subscription.
+type goroutines struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	closing bool
+}
+
+// Go runs fn on a new goroutine of the group and reports whether it did. After wait was called,
+// the group is closing and fn is not run, so no goroutine escapes the wait.
+func (g *goroutines) Go(fn func()) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closing {
+		return false
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn()
+	}()
+	return true
+}
+
+// wait waits until all the goroutines of the group exit, or the context is done.
+func (g *goroutines) wait(ctx context.Context) error {
+	g.mu.Lock()
+	g.closing = true
+	g.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
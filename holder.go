@@ -2,14 +2,18 @@ package sbc
 
 import (
 	"context"
+	"iter"
 	"sync"
 )
 
 // Holder represents a synchronized container that holds a value of any type.
 type Holder[T any] struct {
-	mu    sync.RWMutex
-	cond  *sync.Cond
-	value T
+	mu      sync.RWMutex
+	cond    *sync.Cond
+	value   T
+	version Version
+	// seq is incremented on every change of the value
+	seq uint64
 }
 
 // NewHolder creates a new envelope with the given value
@@ -26,39 +30,146 @@ func (e *Holder[T]) GetValue() T {
 	return e.value
 }
 
+// getValueVersion returns the value of the envelope together with its version
+func (e *Holder[T]) getValueVersion() (T, Version) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.value, e.version
+}
+
 // SetValue sets the value of the envelope
 func (e *Holder[T]) setValue(value T) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.value = value
+	e.seq++
+	// signal all goroutines waiting on the condition
+	e.cond.Broadcast()
+}
+
+// setValueVersion sets the value of the envelope together with its version
+func (e *Holder[T]) setValueVersion(value T, version Version) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.value = value
+	e.version = version
+	e.seq++
 	// signal all goroutines waiting on the condition
 	e.cond.Broadcast()
 }
 
+// setVersion sets the version of the envelope without notifying the waiting goroutines,
+// it is used when an update does not change the value
+func (e *Holder[T]) setVersion(version Version) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.version = version
+}
+
+// current returns the value of the envelope together with its sequence number
+func (e *Holder[T]) current() (T, uint64) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.value, e.seq
+}
+
+// next waits until the value changes after the given sequence number and returns the latest value
+// with its sequence number. It returns false when the context is done first.
+func (e *Holder[T]) next(ctx context.Context, seq uint64) (T, uint64, bool) {
+	// wake up the waiting goroutine when the context is done
+	stop := context.AfterFunc(ctx, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.cond.Broadcast()
+	})
+	defer stop()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for e.seq == seq {
+		if ctx.Err() != nil {
+			var defT T
+			return defT, seq, false
+		}
+		e.cond.Wait()
+	}
+	return e.value, e.seq, true
+}
+
 // Updates returns a receive-only channel that sends updates of type T.
 // The channel is closed when the context is done.
 // It continuously sends the current value of the Holder to the channel.
 // If the context is done, it stops sending updates and closes the channel.
 func (e *Holder[T]) Updates(ctx context.Context) <-chan T {
-	go func() {
-		// unfreeze the goroutine when the context is done
-		<-ctx.Done()
-		e.cond.Signal()
-	}()
+	return updatesOf[T](ctx, e, nil)
+}
+
+// valueHolder is the holder of a subscription value, either a Holder or an AtomicHolder.
+type valueHolder[T any] interface {
+	notifier[T]
+	GetValue() T
+	Updates(ctx context.Context) <-chan T
+	getValueVersion() (T, Version)
+	setValueVersion(value T, version Version)
+	setVersion(version Version)
+}
+
+var (
+	_ valueHolder[any] = (*Holder[any])(nil)
+	_ valueHolder[any] = (*AtomicHolder[any])(nil)
+)
+
+// notifier notifies about the changes of a value, each change has a new sequence number.
+type notifier[T any] interface {
+
+	// current returns the value together with its sequence number.
+	current() (T, uint64)
+
+	// next waits until the value changes after the given sequence number and returns the latest value
+	// with its sequence number. It returns false when the context is done first.
+	next(ctx context.Context, seq uint64) (T, uint64, bool)
+}
+
+// updatesOf returns a channel that sends the changes of the value, it is closed when the context is done.
+// The sending goroutine is started by spawn, or by the go statement if spawn is nil. If spawn refuses
+// to start it, the channel is closed right away.
+func updatesOf[T any](ctx context.Context, n notifier[T], spawn func(func()) bool) <-chan T {
+	if spawn == nil {
+		spawn = goSpawn
+	}
 	out := make(chan T)
-	go func() {
+	_, seq := n.current()
+	started := spawn(func() {
 		defer close(out)
-		for {
-			// wait for the context to be done or the value to change
-			e.mu.Lock()
-			e.cond.Wait()
-			val := e.value
-			e.mu.Unlock()
-			if ctx.Err() != nil {
+		for val := range valuesAfter(ctx, n, seq) {
+			select {
+			case out <- val:
+			case <-ctx.Done():
 				return
 			}
-			out <- val
 		}
-	}()
+	})
+	if !started {
+		close(out)
+	}
 	return out
 }
+
+// goSpawn runs fn on a new goroutine started by the go statement.
+func goSpawn(fn func()) bool {
+	go fn()
+	return true
+}
+
+// valuesAfter returns an iterator over the values set after the given sequence number.
+// A slow consumer skips the intermediate values and gets the latest one.
+func valuesAfter[T any](ctx context.Context, n notifier[T], seq uint64) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			val, next, ok := n.next(ctx, seq)
+			if !ok || !yield(val) {
+				return
+			}
+			seq = next
+		}
+	}
+}
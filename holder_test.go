@@ -34,3 +34,73 @@ func TestHolderUpdatesNoChange(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestHolderUpdatesClosedOnCancel(t *testing.T) {
+	holder := NewHolder[int](10)
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := holder.Updates(ctx)
+	holder.setValue(20)
+	// the update is not received, cancel must still close the channel
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-updates:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("the channel is not closed")
+		}
+	}
+}
+
+// benchmarkHolder is the part of the holders under benchmark.
+type benchmarkHolder interface {
+	GetValue() testConfig
+	setValueVersion(testConfig, Version)
+}
+
+// benchmarkHolders are the holders to compare in the benchmarks.
+var benchmarkHolders = []struct {
+	name      string
+	newHolder func() benchmarkHolder
+}{
+	{"Holder", func() benchmarkHolder { return NewHolder(testConfig{Value: 1}) }},
+	{"AtomicHolder", func() benchmarkHolder { return NewAtomicHolder(testConfig{Value: 1}) }},
+}
+
+func BenchmarkHolderGetValueParallel(b *testing.B) {
+	for _, bh := range benchmarkHolders {
+		b.Run(bh.name, func(b *testing.B) {
+			holder := bh.newHolder()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = holder.GetValue()
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkHolderGetValueParallelWithWriter(b *testing.B) {
+	for _, bh := range benchmarkHolders {
+		b.Run(bh.name, func(b *testing.B) {
+			holder := bh.newHolder()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				for i := 0; ctx.Err() == nil; i++ {
+					holder.setValueVersion(testConfig{Value: i}, Version{Revision: uint64(i)})
+					time.Sleep(time.Microsecond)
+				}
+			}()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = holder.GetValue()
+				}
+			})
+		})
+	}
+}
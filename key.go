@@ -1,5 +1,10 @@
 package sbc
 
+import (
+	"context"
+	"errors"
+)
+
 // KeyBuilder is an interface that defines a method to build a key based on a value of type T.
 // The BuildKey method takes a value of type T as a parameter and returns a string key.
 type KeyBuilder[T any] interface {
@@ -16,3 +21,75 @@ type KeyBuilderFunc[T any] func(t T) string
 func (k KeyBuilderFunc[T]) BuildKey(t T) string {
 	return k(t)
 }
+
+// ContextKeyBuilder is an optional interface of a KeyBuilder that needs the subscription context
+// to build the key, for example to read values stored in the context, and that can fail.
+// The subscription uses BuildKeyContext instead of BuildKey when the KeyBuilder implements it.
+type ContextKeyBuilder[T any] interface {
+	KeyBuilder[T]
+
+	// BuildKeyContext generates a string key based on the provided context and parameter of type T.
+	// Returns an error if the key can not be built.
+	BuildKeyContext(ctx context.Context, t T) (string, error)
+}
+
+// BuildKeyContext builds the key with the given KeyBuilder, using BuildKeyContext if the KeyBuilder
+// implements ContextKeyBuilder and BuildKey otherwise.
+func BuildKeyContext[T any](ctx context.Context, kb KeyBuilder[T], t T) (string, error) {
+	if ckb, ok := kb.(ContextKeyBuilder[T]); ok {
+		return ckb.BuildKeyContext(ctx, t)
+	}
+	return kb.BuildKey(t), nil
+}
+
+// KeyBuilderContextFunc is a type that represents a function that builds a key based on a context and
+// a value of type T. It implements ContextKeyBuilder, so it can read the KeyParams of the subscription.
+//
+// Example usage:
+//
+//	kb := sbc.KeyBuilderContextFunc[Limits](func(ctx context.Context, _ Limits) (string, error) {
+//		return "limits/tenant-" + sbc.KeyParamsFromContext(ctx)["tenant"], nil
+//	})
+type KeyBuilderContextFunc[T any] func(ctx context.Context, t T) (string, error)
+
+// BuildKey returns the key generated with a background context, the error is ignored.
+func (k KeyBuilderContextFunc[T]) BuildKey(t T) string {
+	key, _ := k(context.Background(), t)
+	return key
+}
+
+// BuildKeyContext returns the key generated by the KeyBuilderContextFunc for the given context and input value.
+func (k KeyBuilderContextFunc[T]) BuildKeyContext(ctx context.Context, t T) (string, error) {
+	return k(ctx, t)
+}
+
+// MultiKeyBuilder is an optional interface of a KeyBuilder that resolves to several keys ordered by priority,
+// for example the new key of a config followed by its legacy keys during a key migration.
+// The subscription watches all the keys and uses the value of the highest-priority key that exists.
+type MultiKeyBuilder[T any] interface {
+	KeyBuilder[T]
+
+	// BuildKeys generates the keys based on the provided context and parameter of type T,
+	// ordered from the highest priority to the lowest.
+	BuildKeys(ctx context.Context, t T) ([]string, error)
+}
+
+// BuildKeys builds the keys with the given KeyBuilder, using BuildKeys if the KeyBuilder implements
+// MultiKeyBuilder and BuildKeyContext otherwise.
+func BuildKeys[T any](ctx context.Context, kb KeyBuilder[T], t T) ([]string, error) {
+	if mkb, ok := kb.(MultiKeyBuilder[T]); ok {
+		keys, err := mkb.BuildKeys(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return nil, errors.New("no keys built")
+		}
+		return keys, nil
+	}
+	key, err := BuildKeyContext(ctx, kb, t)
+	if err != nil {
+		return nil, err
+	}
+	return []string{key}, nil
+}
@@ -1,6 +1,7 @@
 package sbc
 
 import (
+	"context"
 	"testing"
 )
 
@@ -31,3 +32,49 @@ func TestKeyBuilderFuncBuildKeyWithStruct(t *testing.T) {
 		t.Errorf("Expected 'TestStruct', got '%s'", result)
 	}
 }
+
+type contextKeyBuilder struct {
+	KeyBuilderFunc[int]
+}
+
+func (contextKeyBuilder) BuildKeyContext(ctx context.Context, _ int) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return "from-context", nil
+}
+
+func TestBuildKeyContextWithKeyBuilder(t *testing.T) {
+	builder := KeyBuilderFunc[int](func(i int) string { return "int" })
+	result, err := BuildKeyContext[int](context.Background(), builder, 1)
+	if err != nil || result != "int" {
+		t.Errorf("Expected 'int', got '%s', %v", result, err)
+	}
+}
+
+func TestBuildKeyContextWithContextKeyBuilder(t *testing.T) {
+	builder := contextKeyBuilder{KeyBuilderFunc[int](func(i int) string { return "int" })}
+	result, err := BuildKeyContext[int](context.Background(), builder, 1)
+	if err != nil || result != "from-context" {
+		t.Errorf("Expected 'from-context', got '%s', %v", result, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := BuildKeyContext[int](ctx, builder, 1); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestKeyBuilderContextFuncWithParams(t *testing.T) {
+	builder := KeyBuilderContextFunc[int](func(ctx context.Context, _ int) (string, error) {
+		return "limits/tenant-" + KeyParamsFromContext(ctx)["tenant"], nil
+	})
+	ctx := ContextWithKeyParams(context.Background(), KeyParams{"tenant": "42"})
+	result, err := BuildKeyContext[int](ctx, builder, 0)
+	if err != nil || result != "limits/tenant-42" {
+		t.Errorf("Expected 'limits/tenant-42', got '%s', %v", result, err)
+	}
+	if result := builder.BuildKey(0); result != "limits/tenant-" {
+		t.Errorf("Expected 'limits/tenant-', got '%s'", result)
+	}
+}
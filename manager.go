@@ -0,0 +1,483 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrManagerStarted is an error that is returned when a Manager is started twice, or a subscription is added
+// to a started Manager.
+var ErrManagerStarted = errors.New("manager already started")
+
+// State is the state of a subscription managed by a Manager.
+type State int32
+
+const (
+	// StatePending means that the subscription is not started yet.
+	StatePending State = iota
+
+	// StateStarting means that the subscription is getting its initial value.
+	StateStarting
+
+	// StateRunning means that the subscription has a value and follows its updates.
+	StateRunning
+
+	// StateFailed means that the subscription failed to start, see Status.Err.
+	StateFailed
+
+	// StateStopped means that the subscription was stopped by Shutdown.
+	StateStopped
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateFailed:
+		return "failed"
+	case StateStopped:
+		return "stopped"
+	default:
+		return fmt.Sprintf("State(%d)", int32(s))
+	}
+}
+
+// Status is the status of a subscription managed by a Manager.
+type Status struct {
+	Name     string
+	Required bool
+	State    State
+
+	// Err is the error the subscription failed to start with.
+	Err error
+
+	// Version is the version of the current value of a running subscription.
+	Version Version
+}
+
+// Manager manages all the config subscriptions of a process: it starts them concurrently with a global
+// timeout, fails fast when a required one cannot start, tolerates the optional ones, exposes their status,
+// and shuts them all down. The running subscriptions are registered in the Registry of the Manager,
+// so Snapshot gives a coherent view of all of them.
+//
+//	m := sbc.NewManager(sbc.WithStartTimeout(10 * time.Second))
+//	db, _ := sbc.Manage(m, "db", dbSubscriber)
+//	flags, _ := sbc.Manage(m, "flags", flagsSubscriber, sbc.AsOptional())
+//	if err := m.Start(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+//	defer m.Shutdown(context.Background())
+type Manager struct {
+	opts     managerOpts
+	registry *Registry
+
+	mu      sync.Mutex
+	entries []managedEntry
+	started bool
+}
+
+// managedEntry is a subscription managed by a Manager.
+type managedEntry interface {
+	name() string
+	required() bool
+	start(ctx context.Context, r *Registry) error
+	stop(ctx context.Context, r *Registry) error
+	waitLate(ctx context.Context) error
+	status() Status
+	Readier
+}
+
+// NewManager creates a new Manager with the given options
+func NewManager(opts ...ManagerOpt) *Manager {
+	return &Manager{opts: newDefaultManagerOpts().apply(opts), registry: NewRegistry()}
+}
+
+// Manage adds a subscription of the subscriber to the manager under the name, the subscription is started
+// by Manager.Start. The name must be unique, it is the name of the subscription in the Registry.
+func Manage[T any](m *Manager, name string, s *Subscriber[T], opts ...ManageOpt) (*Managed[T], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.started {
+		return nil, ErrManagerStarted
+	}
+	if slices.ContainsFunc(m.entries, func(e managedEntry) bool { return e.name() == name }) {
+		return nil, fmt.Errorf("name '%s': %w", name, ErrAlreadyRegistered)
+	}
+	o := manageOpts{required: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	managed := &Managed[T]{entryName: name, subscriber: s, opts: o}
+	m.entries = append(m.entries, managed)
+	return managed, nil
+}
+
+// Start starts all the managed subscriptions concurrently, and waits until all of them get their initial
+// value, the start timeout elapses, or a required subscription fails. It fails if a required subscription
+// fails to start, and stops the other ones in this case, waiting for them at most the start timeout.
+// The error reports the subscriptions that failed, not the ones stopped because of them.
+// The optional subscriptions that fail to start are logged and reported by Status.
+func (m *Manager) Start(parent context.Context) error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return ErrManagerStarted
+	}
+	m.started = true
+	entries := slices.Clone(m.entries)
+	m.mu.Unlock()
+
+	ctx, abort := context.WithCancelCause(parent)
+	defer abort(nil)
+	ctx, cancel := context.WithTimeout(ctx, m.opts.startTimeout)
+	defer cancel()
+	errs := make([]error, len(entries))
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.start(ctx, m.registry); err != nil {
+				errs[i] = fmt.Errorf("config '%s': %w", e.name(), err)
+				if e.required() {
+					// fail fast, the other subscriptions are stopped anyway
+					abort(errStartAborted)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	var required []error
+	for i, e := range entries {
+		switch {
+		case errs[i] == nil, errors.Is(errs[i], errStartAborted):
+		case e.required():
+			required = append(required, errs[i])
+		default:
+			m.opts.logger.Warn("optional config failed to start", "config", e.name(), "error", errs[i])
+		}
+	}
+	if len(required) > 0 {
+		err := fmt.Errorf("failed to start required configs: %w", errors.Join(required...))
+		// the subscriptions that started are closed, a transport that does not end its watches can not
+		// make Start wait past the start timeout
+		stopCtx, cancelStop := context.WithTimeout(parent, m.opts.startTimeout)
+		defer cancelStop()
+		if stopErr := m.stopAll(stopCtx, entries); stopErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to stop configs: %w", stopErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// errStartAborted is the cause of the start context of the subscriptions that are stopped because a required
+// subscription failed to start.
+var errStartAborted = errors.New("another required config failed to start")
+
+// Status returns the status of all the managed subscriptions, in the order they were added.
+func (m *Manager) Status() []Status {
+	m.mu.Lock()
+	entries := slices.Clone(m.entries)
+	m.mu.Unlock()
+	statuses := make([]Status, 0, len(entries))
+	for _, e := range entries {
+		statuses = append(statuses, e.status())
+	}
+	return statuses
+}
+
+// Ready reports whether all the required subscriptions are running and have their value,
+// e.g. for a readiness probe. The optional ones are not waited for.
+func (m *Manager) Ready() bool {
+	for _, e := range m.requiredEntries() {
+		if !e.Ready() {
+			return false
+		}
+	}
+	return true
+}
+
+// WaitReady waits until all the required subscriptions have their value, see WithAsyncStart.
+// It fails with ErrNotReady when a required subscription is not started or failed to start.
+func (m *Manager) WaitReady(ctx context.Context) error {
+	entries := m.requiredEntries()
+	rs := make([]Readier, 0, len(entries))
+	for _, e := range entries {
+		rs = append(rs, e)
+	}
+	return WaitAllReady(ctx, rs...)
+}
+
+// requiredEntries returns the required managed subscriptions.
+func (m *Manager) requiredEntries() []managedEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var entries []managedEntry
+	for _, e := range m.entries {
+		if e.required() {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Registry returns the registry the running subscriptions are registered in.
+func (m *Manager) Registry() *Registry {
+	return m.registry
+}
+
+// Snapshot returns the current values of all the running subscriptions, captured atomically, see Registry.Snapshot.
+func (m *Manager) Snapshot() Snapshot {
+	return m.registry.Snapshot()
+}
+
+// Shutdown stops all the managed subscriptions, in the reverse order they were added, and waits until
+// their goroutines exit or the context is done, see Subscription.Close. It also waits for the subscriptions
+// that started after the start timeout, which are closed. It returns the errors of the subscriptions.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	entries := slices.Clone(m.entries)
+	m.mu.Unlock()
+	errs := []error{m.stopAll(ctx, entries)}
+	for _, e := range entries {
+		if err := e.waitLate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("config '%s': %w", e.name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// waitLate waits until a subscription that started after the start timeout is closed.
+func (e *Managed[T]) waitLate(ctx context.Context) error {
+	if err := e.late.wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait for the late subscription: %w", err)
+	}
+	return nil
+}
+
+// stopAll stops the entries in the reverse order.
+func (m *Manager) stopAll(ctx context.Context, entries []managedEntry) error {
+	var errs []error
+	for _, e := range slices.Backward(entries) {
+		if err := e.stop(ctx, m.registry); err != nil {
+			errs = append(errs, fmt.Errorf("config '%s': %w", e.name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Managed is a subscription managed by a Manager, see Manage.
+type Managed[T any] struct {
+	entryName  string
+	subscriber *Subscriber[T]
+	opts       manageOpts
+
+	mu     sync.Mutex
+	sub    *Subscription[T]
+	cancel context.CancelFunc
+	state  atomic.Int32
+	err    error
+	// late is the goroutine that closes a subscription started after the start timeout, Shutdown waits for it
+	late goroutines
+}
+
+// Subscription returns the subscription, or nil if it is not running.
+func (e *Managed[T]) Subscription() *Subscription[T] {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.sub
+}
+
+// Status returns the status of the subscription.
+func (e *Managed[T]) Status() Status {
+	return e.status()
+}
+
+func (e *Managed[T]) name() string {
+	return e.entryName
+}
+
+func (e *Managed[T]) required() bool {
+	return e.opts.required
+}
+
+// start subscribes and registers the subscription. The lifetime of the subscription does not depend on ctx,
+// which only limits the time to get the initial value.
+func (e *Managed[T]) start(ctx context.Context, r *Registry) error {
+	e.state.Store(int32(StateStarting))
+	subCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	if e.opts.params != nil {
+		subCtx = ContextWithKeyParams(subCtx, e.opts.params)
+	}
+	type result struct {
+		sub *Subscription[T]
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		subscribe := e.subscriber.Subscribe
+		if e.opts.async {
+			subscribe = e.subscriber.SubscribeAsync
+		}
+		sub, err := subscribe(subCtx)
+		done <- result{sub, err}
+	}()
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		res.err = fmt.Errorf("failed to start subscription: %w", context.Cause(ctx))
+		// the subscribe may still succeed, its context is canceled below and the subscription is closed
+		e.late.Go(func() {
+			if late := <-done; late.sub != nil {
+				_ = late.sub.Close(context.Background())
+			}
+		})
+	}
+	if res.err == nil {
+		res.err = Register(r, e.entryName, res.sub)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if res.err != nil {
+		cancel()
+		e.err = res.err
+		e.state.Store(int32(StateFailed))
+		return res.err
+	}
+	e.sub, e.cancel = res.sub, cancel
+	e.state.Store(int32(StateRunning))
+	return nil
+}
+
+// stop unregisters and closes the subscription if it is running.
+func (e *Managed[T]) stop(ctx context.Context, r *Registry) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.sub == nil {
+		return nil
+	}
+	_ = r.Unregister(e.entryName)
+	err := e.sub.Close(ctx)
+	e.cancel()
+	e.sub = nil
+	e.state.Store(int32(StateStopped))
+	return err
+}
+
+func (e *Managed[T]) status() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := Status{Name: e.entryName, Required: e.opts.required, State: State(e.state.Load()), Err: e.err}
+	if e.sub != nil {
+		s.Version = e.sub.Version()
+		if !e.sub.Ready() {
+			// an async subscription is starting until it has its value
+			s.State = StateStarting
+		}
+	}
+	return s
+}
+
+// Ready reports whether the subscription is running and has its value.
+func (e *Managed[T]) Ready() bool {
+	return e.status().State == StateRunning
+}
+
+// WaitReady waits until the subscription has its value. It fails with ErrNotReady when the subscription
+// is not started or failed to start.
+func (e *Managed[T]) WaitReady(ctx context.Context) error {
+	sub := e.Subscription()
+	if sub == nil {
+		s := e.status()
+		return fmt.Errorf("config '%s' is %s: %w", e.entryName, s.State, ErrNotReady)
+	}
+	if err := sub.WaitReady(ctx); err != nil {
+		return fmt.Errorf("config '%s': %w", e.entryName, err)
+	}
+	return nil
+}
+
+// ManagerOpt is a function type used to configure a Manager instance.
+type ManagerOpt func(*managerOpts)
+
+// managerOpts is a struct that holds the options of a Manager instance.
+type managerOpts struct {
+	startTimeout time.Duration
+	logger       *slog.Logger
+}
+
+// newDefaultManagerOpts creates a new manager options with the default values
+func newDefaultManagerOpts() managerOpts {
+	return managerOpts{
+		startTimeout: 30 * time.Second,
+		logger:       slog.Default(),
+	}
+}
+
+// apply applies a list of ManagerOpts to the managerOpts receiver and returns the modified copy.
+func (o managerOpts) apply(opts []ManagerOpt) managerOpts {
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithStartTimeout sets how long Manager.Start waits for all the subscriptions to start, 30 seconds by default.
+func WithStartTimeout(timeout time.Duration) ManagerOpt {
+	return func(o *managerOpts) {
+		o.startTimeout = timeout
+	}
+}
+
+// WithManagerLogger sets the logger of a Manager instance, slog.Default() by default.
+func WithManagerLogger(logger *slog.Logger) ManagerOpt {
+	return func(o *managerOpts) {
+		o.logger = logger
+	}
+}
+
+// ManageOpt is a function type used to configure a subscription managed by a Manager.
+type ManageOpt func(*manageOpts)
+
+// manageOpts is a struct that holds the options of a managed subscription.
+type manageOpts struct {
+	required bool
+	async    bool
+	params   KeyParams
+}
+
+// AsOptional makes the subscription optional: the Manager starts without it if it fails to start.
+// Subscriptions are required by default.
+func AsOptional() ManageOpt {
+	return func(o *manageOpts) {
+		o.required = false
+	}
+}
+
+// WithManagedParams sets the key params of the subscription, see Subscriber.SubscribeWithParams.
+func WithManagedParams(params KeyParams) ManageOpt {
+	return func(o *manageOpts) {
+		o.params = params
+	}
+}
+
+// WithAsyncStart makes Manager.Start subscribe with Subscriber.SubscribeAsync, without waiting for
+// the initial value. Use Manager.WaitReady or Manager.Ready to wait for the required ones.
+func WithAsyncStart() ManageOpt {
+	return func(o *manageOpts) {
+		o.async = true
+	}
+}
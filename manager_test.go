@@ -0,0 +1,211 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingTransport is a transport whose keys never get a value, Current blocks until ctx is done.
+type blockingTransport struct {
+	legacyTransport
+}
+
+func (blockingTransport) CurrentUpdate(ctx context.Context, _ string) (Update, error) {
+	<-ctx.Done()
+	return Update{}, ctx.Err()
+}
+
+func (blockingTransport) WatchUpdates(context.Context, string) (<-chan Update, error) {
+	return nil, nil
+}
+
+// newTestSubscriber creates a Subscriber of the key of the transport.
+func newTestSubscriber(transport Transport, key string) *Subscriber[testConfig] {
+	return NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return key }))
+}
+
+func TestManager(t *testing.T) {
+	transport := newMockTransport(map[string]string{"a": `{"value": 1}`, "b": `{"value": 2}`})
+	m := NewManager(WithStartTimeout(100*time.Millisecond), WithManagerLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	a, err := Manage(m, "a", newTestSubscriber(transport, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Manage(m, "a", newTestSubscriber(transport, "a")); !errors.Is(err, ErrAlreadyRegistered) {
+		t.Errorf("unexpected error %v", err)
+	}
+	_, _ = Manage(m, "b", newTestSubscriber(transport, "b"))
+	missing, _ := Manage(m, "missing", newTestSubscriber(transport, "missing"), AsOptional())
+	slow, _ := Manage(m, "slow", newTestSubscriber(blockingTransport{}, "slow"), AsOptional())
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(context.Background()); !errors.Is(err, ErrManagerStarted) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if a.Subscription().Get().Value != 1 {
+		t.Errorf("Expected 1, got %d", a.Subscription().Get().Value)
+	}
+	if s := missing.Status(); s.State != StateFailed || s.Err == nil {
+		t.Errorf("unexpected status %+v", s)
+	}
+	if s := slow.Status(); s.State != StateFailed || !errors.Is(s.Err, context.DeadlineExceeded) {
+		t.Errorf("unexpected status %+v", s)
+	}
+	statuses := m.Status()
+	if len(statuses) != 4 || statuses[1].Name != "b" || statuses[1].State != StateRunning || statuses[1].Version.Key != "b" {
+		t.Errorf("unexpected statuses %+v", statuses)
+	}
+	if val, ok := SnapshotValue[testConfig](m.Snapshot(), "b"); !ok || val.Value != 2 {
+		t.Errorf("unexpected value %+v", val)
+	}
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := a.Status(); s.State != StateStopped || a.Subscription() != nil {
+		t.Errorf("unexpected status %+v", s)
+	}
+	if names := m.Registry().Names(); len(names) != 0 {
+		t.Errorf("unexpected names %v", names)
+	}
+}
+
+func TestManagerRequiredFails(t *testing.T) {
+	transport := newMockTransport(map[string]string{"a": `{"value": 1}`})
+	m := NewManager(WithStartTimeout(time.Second))
+	a, _ := Manage(m, "a", newTestSubscriber(transport, "a"))
+	slow, _ := Manage(m, "slow", newTestSubscriber(blockingTransport{}, "slow"))
+	_, _ = Manage(m, "missing", newTestSubscriber(transport, "missing"))
+	start := time.Now()
+	err := m.Start(context.Background())
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	// only the config that failed is reported, not the one stopped because of it
+	if !strings.Contains(err.Error(), "'missing'") || strings.Contains(err.Error(), "'slow'") {
+		t.Errorf("unexpected error %v", err)
+	}
+	// the missing required config fails fast, without waiting for the timeout
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Start took %s", elapsed)
+	}
+	if a.Subscription() != nil || slow.Status().State != StateFailed {
+		t.Errorf("unexpected statuses %+v", m.Status())
+	}
+	if _, err := Manage(m, "late", newTestSubscriber(transport, "a")); !errors.Is(err, ErrManagerStarted) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// releasedTransport is a transport whose Current ignores the context and returns once released.
+type releasedTransport struct {
+	legacyTransport
+	release  chan struct{}
+	watching atomic.Int32
+}
+
+func (rt *releasedTransport) CurrentUpdate(_ context.Context, key string) (Update, error) {
+	<-rt.release
+	return Update{Key: key, Value: []byte(`{"value": 1}`)}, nil
+}
+
+func (rt *releasedTransport) WatchUpdates(ctx context.Context, _ string) (<-chan Update, error) {
+	rt.watching.Add(1)
+	ch := make(chan Update)
+	go func() {
+		<-ctx.Done()
+		rt.watching.Add(-1)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func TestManagerClosesLateSubscription(t *testing.T) {
+	transport := &releasedTransport{release: make(chan struct{})}
+	m := NewManager(WithStartTimeout(10*time.Millisecond), WithManagerLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	late, _ := Manage(m, "late", newTestSubscriber(transport, "late"))
+	if err := m.Start(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// the subscribe succeeds after the timeout, the subscription is closed instead of kept
+	close(transport.release)
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if late.Subscription() != nil || transport.watching.Load() != 0 {
+		t.Errorf("the late subscription is running, %d watches", transport.watching.Load())
+	}
+}
+
+func TestManagerSharedSubscriber(t *testing.T) {
+	transport := newMockTransport(map[string]string{"tenants/1": `{"value": 1}`, "tenants/2": `{"value": 2}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderContextFunc[testConfig](func(ctx context.Context, _ testConfig) (string, error) {
+		return "tenants/" + KeyParamsFromContext(ctx)["tenant"], nil
+	}))
+	m := NewManager()
+	one, _ := Manage(m, "one", sub, WithManagedParams(KeyParams{"tenant": "1"}))
+	two, _ := Manage(m, "two", sub, WithManagedParams(KeyParams{"tenant": "2"}))
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = m.Shutdown(context.Background()) }()
+	if one.Subscription().Get().Value != 1 || two.Subscription().Get().Value != 2 {
+		t.Errorf("unexpected values %+v, %+v", one.Subscription().Get(), two.Subscription().Get())
+	}
+}
+
+// stuckTransport is a transport whose watches end only when released, whatever the context.
+type stuckTransport struct {
+	legacyTransport
+	release chan struct{}
+}
+
+func (stuckTransport) CurrentUpdate(_ context.Context, key string) (Update, error) {
+	return Update{Key: key, Value: []byte(`{"value": 1}`)}, nil
+}
+
+func (s stuckTransport) WatchUpdates(context.Context, string) (<-chan Update, error) {
+	ch := make(chan Update)
+	go func() {
+		<-s.release
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// slowMissingTransport is a transport whose keys are reported missing after a delay.
+type slowMissingTransport struct {
+	legacyTransport
+}
+
+func (slowMissingTransport) CurrentUpdate(context.Context, string) (Update, error) {
+	time.Sleep(20 * time.Millisecond)
+	return Update{}, errMockNotFound
+}
+
+func (slowMissingTransport) WatchUpdates(context.Context, string) (<-chan Update, error) {
+	return nil, nil
+}
+
+func TestManagerStartStopBounded(t *testing.T) {
+	transport := stuckTransport{release: make(chan struct{})}
+	t.Cleanup(func() { close(transport.release) })
+	m := NewManager(WithStartTimeout(100*time.Millisecond), WithManagerLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	_, _ = Manage(m, "stuck", newTestSubscriber(transport, "stuck"))
+	_, _ = Manage(m, "missing", newTestSubscriber(slowMissingTransport{}, "missing"))
+	start := time.Now()
+	err := m.Start(context.Background())
+	// the stuck subscription can not be stopped, Start returns after the start timeout with the stop error
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Start took %s", elapsed)
+	}
+	if !errors.Is(err, errMockNotFound) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
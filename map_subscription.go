@@ -0,0 +1,148 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// ErrPrefixNotSupported is an error that is returned when the transport does not implement PrefixTransport.
+var ErrPrefixNotSupported = errors.New("transport does not support prefix subscriptions")
+
+// MapSubscription represents a subscription to all keys under a common prefix, like one entry per tenant
+// or per feature. It keeps a live map of the decoded values, indexed by the key without the prefix,
+// in sync with the adds, updates and deletes received from the transport.
+//
+// Values that fail to decode are left out of the map, a failed update of a key keeps its previous value.
+type MapSubscription[T any] struct {
+	transport PrefixTransport
+	encoder   Encoder
+	prefix    string
+
+	holder     *Holder[map[string]T]
+	goroutines goroutines
+	// revisions are the revisions of the keys, used only by the update goroutine after the start
+	revisions map[string]uint64
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// SubscribePrefix starts a new subscription to all keys starting with the prefix.
+// The transport of the Subscriber must implement PrefixTransport, the key builder is not used.
+func (s *Subscriber[T]) SubscribePrefix(ctx context.Context, prefix string) (*MapSubscription[T], error) {
+	pt, ok := s.transport.(PrefixTransport)
+	if !ok {
+		return nil, fmt.Errorf("failed to start prefix subscription: %w", ErrPrefixNotSupported)
+	}
+	sub := &MapSubscription[T]{transport: pt, encoder: s.opts.encoder, prefix: prefix}
+	if err := sub.start(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start prefix subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// Unsubscribe permanently stops the MapSubscription and cancel the context.
+func (sub *MapSubscription[T]) Unsubscribe() {
+	sub.cancel()
+}
+
+// Close permanently stops the MapSubscription like Unsubscribe, and waits until all its goroutines exit,
+// see Subscription.Close.
+func (sub *MapSubscription[T]) Close(ctx context.Context) error {
+	sub.cancel()
+	if err := sub.goroutines.wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait for the subscription goroutines: %w", err)
+	}
+	return nil
+}
+
+// Prefix returns the prefix of the subscription.
+func (sub *MapSubscription[T]) Prefix() string {
+	return sub.prefix
+}
+
+// Get returns a copy of the current map of values, indexed by the key without the prefix.
+func (sub *MapSubscription[T]) Get() map[string]T {
+	return maps.Clone(sub.holder.GetValue())
+}
+
+// GetKey returns the current value of the key, given without the prefix.
+func (sub *MapSubscription[T]) GetKey(key string) (T, bool) {
+	val, ok := sub.holder.GetValue()[key]
+	return val, ok
+}
+
+// GetUpdates returns a receive-only channel that sends the whole map after every change.
+// The channel is closed when the subscription is stopped. The maps sent to the channel are shared
+// between all receivers and must not be modified.
+func (sub *MapSubscription[T]) GetUpdates() <-chan map[string]T {
+	return updatesOf[map[string]T](sub.ctx, sub.holder, sub.goroutines.Go)
+}
+
+// start gets the initial values and receives the updates of the prefix from the transport.
+// The updates are watched before the initial values are read, so the changes made in between are not lost.
+func (sub *MapSubscription[T]) start(ctx context.Context) error {
+	sub.ctx, sub.cancel = context.WithCancel(ctx)
+	updates, err := sub.transport.UpdatesPrefix(sub.ctx, sub.prefix)
+	if err != nil {
+		sub.cancel()
+		return fmt.Errorf("failed to get updates from transport: %w", err)
+	}
+	current, err := sub.transport.CurrentPrefix(sub.ctx, sub.prefix)
+	if err != nil {
+		sub.cancel()
+		return fmt.Errorf("failed to get current values: %w", err)
+	}
+	values := make(map[string]T, len(current))
+	sub.revisions = make(map[string]uint64, len(current))
+	for _, upd := range current {
+		key := strings.TrimPrefix(upd.Key, sub.prefix)
+		sub.revisions[key] = upd.Revision
+		val, err := decodeUpdate[T](sub.encoder, upd)
+		if err != nil {
+			continue
+		}
+		values[key] = val
+	}
+	sub.holder = NewHolder(values)
+	// the updates are applied until the transport closes them, which also drains them after Unsubscribe
+	sub.goroutines.Go(func() {
+		for upd := range updates {
+			if sub.ctx.Err() == nil {
+				sub.apply(upd)
+			}
+		}
+	})
+	return nil
+}
+
+// apply applies the update to a copy of the current map and stores the copy in the holder,
+// so the maps already handed out are never modified. An update that is not newer than the revision
+// of the key, like a change the initial values already include, is skipped.
+func (sub *MapSubscription[T]) apply(upd Update) {
+	key := strings.TrimPrefix(upd.Key, sub.prefix)
+	if upd.Revision != 0 {
+		if upd.Revision <= sub.revisions[key] {
+			return
+		}
+		sub.revisions[key] = upd.Revision
+	}
+	current := sub.holder.GetValue()
+	next := maps.Clone(current)
+	switch upd.Operation {
+	case OpDelete:
+		if _, ok := current[key]; !ok {
+			return
+		}
+		delete(next, key)
+	default:
+		val, err := decodeUpdate[T](sub.encoder, upd)
+		if err != nil {
+			return
+		}
+		next[key] = val
+	}
+	sub.holder.setValue(next)
+}
@@ -0,0 +1,109 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// currentOnlyTransport is a Transport without prefix support.
+type currentOnlyTransport struct{}
+
+func (currentOnlyTransport) Current(context.Context, string) ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+func (currentOnlyTransport) Updates(context.Context, string) (<-chan []byte, error) {
+	return nil, nil
+}
+
+func TestSubscribePrefixNotSupported(t *testing.T) {
+	sub := NewSubscriber[testConfig](currentOnlyTransport{}, KeyBuilderFunc[testConfig](func(testConfig) string { return "" }))
+	if _, err := sub.SubscribePrefix(context.Background(), "tenants/"); !errors.Is(err, ErrPrefixNotSupported) {
+		t.Errorf("Expected ErrPrefixNotSupported, got %v", err)
+	}
+}
+
+func TestSubscribePrefix(t *testing.T) {
+	transport := newMockTransport(map[string]string{
+		"tenants/1": `{"value": 1}`,
+		"tenants/2": `{"value": 2}`,
+		"tenants/3": `invalid`,
+		"other/1":   `{"value": 10}`,
+	})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "" }))
+	mapSubs, err := sub.SubscribePrefix(context.Background(), "tenants/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapSubs.Unsubscribe()
+	values := mapSubs.Get()
+	if len(values) != 2 || values["1"].Value != 1 || values["2"].Value != 2 {
+		t.Fatalf("unexpected values %v", values)
+	}
+	// the returned map is a copy
+	delete(values, "1")
+	if _, ok := mapSubs.GetKey("1"); !ok {
+		t.Error("the subscription map was modified")
+	}
+
+	transport.put("tenants/4", `{"value": 4}`)
+	transport.put("tenants/2", `{"value": 20}`)
+	transport.delete("tenants/1")
+	transport.put("other/2", `{"value": 30}`)
+	waitFor(t, func() bool {
+		values := mapSubs.Get()
+		return len(values) == 2 && values["2"].Value == 20 && values["4"].Value == 4
+	})
+	// an invalid update keeps the previous value
+	transport.put("tenants/4", `invalid`)
+	transport.put("tenants/5", `{"value": 5}`)
+	waitFor(t, func() bool { return len(mapSubs.Get()) == 3 })
+	if val, _ := mapSubs.GetKey("4"); val.Value != 4 {
+		t.Errorf("Expected 4, got %d", val.Value)
+	}
+}
+
+// gapTransport is a mockTransport that runs a function right before the current values of a prefix are read.
+type gapTransport struct {
+	*mockTransport
+	gap func()
+}
+
+func (g *gapTransport) CurrentPrefix(ctx context.Context, prefix string) (map[string]Update, error) {
+	g.gap()
+	return g.mockTransport.CurrentPrefix(ctx, prefix)
+}
+
+func TestSubscribePrefixChangesBeforeCurrent(t *testing.T) {
+	mock := newMockTransport(map[string]string{
+		"tenants/1": `{"value": 1}`,
+		"tenants/2": `{"value": 2}`,
+	})
+	// the changes made after the watch started and before the current values are read are not lost,
+	// and the ones the current values include are not applied again
+	transport := &gapTransport{mockTransport: mock, gap: func() {
+		mock.delete("tenants/1")
+		mock.put("tenants/2", `{"value": 20}`)
+		mock.put("tenants/2", `{"value": 21}`)
+	}}
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "" }))
+	mapSubs, err := sub.SubscribePrefix(context.Background(), "tenants/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapSubs.Unsubscribe()
+	mock.put("tenants/3", `{"value": 3}`)
+	waitFor(t, func() bool {
+		_, ok := mapSubs.GetKey("3")
+		return ok
+	})
+	values := mapSubs.Get()
+	if _, ok := values["1"]; ok || len(values) != 2 || values["2"].Value != 21 {
+		t.Errorf("unexpected values %v", values)
+	}
+	// only the update of the new key changed the map
+	if _, seq := mapSubs.holder.current(); seq != 1 {
+		t.Errorf("Expected 1 change, got %d", seq)
+	}
+}
@@ -0,0 +1,136 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errMockNotFound is returned by the mockTransport for keys without a value.
+var errMockNotFound = errors.New("key not found")
+
+// mockTransport is an in-memory Transport and UpdateTransport, changes are pushed to the subscribers
+// with put and delete. Every change increments the revision.
+type mockTransport struct {
+	mu       sync.Mutex
+	revision uint64
+	values   map[string]Update
+	watchers map[string][]chan Update
+	prefixes map[string][]chan Update
+}
+
+// newMockTransport creates a new mockTransport with the given values.
+func newMockTransport(values map[string]string) *mockTransport {
+	m := &mockTransport{values: map[string]Update{}, watchers: map[string][]chan Update{}, prefixes: map[string][]chan Update{}}
+	for k, v := range values {
+		m.revision++
+		m.values[k] = Update{Key: k, Value: []byte(v), Revision: m.revision, Operation: OpPut}
+	}
+	return m
+}
+
+func (m *mockTransport) Current(ctx context.Context, key string) ([]byte, error) {
+	upd, err := m.CurrentUpdate(ctx, key)
+	return upd.Value, err
+}
+
+func (m *mockTransport) Updates(ctx context.Context, key string) (<-chan []byte, error) {
+	updates, _ := m.WatchUpdates(ctx, key)
+	ch := make(chan []byte)
+	go func() {
+		defer close(ch)
+		for upd := range updates {
+			if upd.Operation == OpPut {
+				ch <- upd.Value
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (m *mockTransport) CurrentUpdate(_ context.Context, key string) (Update, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upd, ok := m.values[key]
+	if !ok {
+		return Update{}, errMockNotFound
+	}
+	return upd, nil
+}
+
+func (m *mockTransport) WatchUpdates(ctx context.Context, key string) (<-chan Update, error) {
+	return m.subscribe(ctx, m.watchers, key), nil
+}
+
+func (m *mockTransport) CurrentPrefix(_ context.Context, prefix string) (map[string]Update, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := map[string]Update{}
+	for k, upd := range m.values {
+		if strings.HasPrefix(k, prefix) {
+			values[k] = upd
+		}
+	}
+	return values, nil
+}
+
+func (m *mockTransport) UpdatesPrefix(ctx context.Context, prefix string) (<-chan Update, error) {
+	return m.subscribe(ctx, m.prefixes, prefix), nil
+}
+
+// subscribe registers a watcher channel under the name, the channel is removed and closed when ctx is done.
+func (m *mockTransport) subscribe(ctx context.Context, registry map[string][]chan Update, name string) <-chan Update {
+	ch := make(chan Update, 16)
+	m.mu.Lock()
+	registry[name] = append(registry[name], ch)
+	m.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, w := range registry[name] {
+			if w == ch {
+				registry[name] = append(registry[name][:i], registry[name][i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// put stores the value and sends it to the watchers of the key.
+func (m *mockTransport) put(key, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revision++
+	upd := Update{Key: key, Value: []byte(value), Revision: m.revision, Timestamp: time.Now(), Operation: OpPut}
+	m.values[key] = upd
+	m.notify(upd)
+}
+
+// delete deletes the key and sends the delete to the watchers of the key.
+func (m *mockTransport) delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revision++
+	delete(m.values, key)
+	m.notify(Update{Key: key, Revision: m.revision, Timestamp: time.Now(), Operation: OpDelete})
+}
+
+// notify sends the update to the watchers of the key and of all matching prefixes, the lock must be held.
+func (m *mockTransport) notify(upd Update) {
+	for _, w := range m.watchers[upd.Key] {
+		w <- upd
+	}
+	for prefix, watchers := range m.prefixes {
+		if !strings.HasPrefix(upd.Key, prefix) {
+			continue
+		}
+		for _, w := range watchers {
+			w <- upd
+		}
+	}
+}
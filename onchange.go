@@ -0,0 +1,176 @@
+package sbc
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrHandlerTimeout is an error that is returned when an OnChange handler does not return in time.
+	ErrHandlerTimeout = errors.New("handler timed out")
+
+	// ErrHandlerPanic is an error that is returned when an OnChange handler panics.
+	ErrHandlerPanic = errors.New("handler panicked")
+)
+
+// ErrorPolicy defines what an error returned by an OnChange handler means.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyLog logs the error, the new value stays. It is the default.
+	ErrorPolicyLog ErrorPolicy = iota
+
+	// ErrorPolicyRollback logs the error and rolls the subscription back to the previous value:
+	// the remaining handlers are not called, and the handlers that were already called get the change back
+	// from the new to the previous value. The handlers run after the new value is stored, so Get, GetUpdates
+	// and All may return the new value before it is rolled back, followed by the previous value again.
+	// Use a Participant to reject a value before anyone sees it.
+	ErrorPolicyRollback
+)
+
+// OnChangeOpt is a function type used to configure an OnChange handler.
+type OnChangeOpt func(*onChangeOpts)
+
+// onChangeOpts is a struct that holds the options of an OnChange handler.
+type onChangeOpts struct {
+	priority int
+	timeout  time.Duration
+	policy   ErrorPolicy
+}
+
+// WithHandlerPriority sets the priority of the handler. Handlers are called in ascending order of priority,
+// the handlers with the same priority in the order of registration. The default priority is 0.
+func WithHandlerPriority(priority int) OnChangeOpt {
+	return func(o *onChangeOpts) {
+		o.priority = priority
+	}
+}
+
+// WithHandlerTimeout sets how long the subscription waits for the handler. A handler that does not return
+// in time fails with ErrHandlerTimeout and is left running in the background. Zero, the default, waits forever.
+// Subscription.Close waits for the handlers left running as well, so a handler that never returns makes Close
+// wait until its context is done: give Close a context with a deadline.
+func WithHandlerTimeout(timeout time.Duration) OnChangeOpt {
+	return func(o *onChangeOpts) {
+		o.timeout = timeout
+	}
+}
+
+// WithErrorPolicy sets what an error returned by the handler means, ErrorPolicyLog by default.
+func WithErrorPolicy(policy ErrorPolicy) OnChangeOpt {
+	return func(o *onChangeOpts) {
+		o.policy = policy
+	}
+}
+
+// changeHandler is a registered OnChange handler.
+type changeHandler[T any] struct {
+	id   uint64
+	fn   func(old, new T) error
+	opts onChangeOpts
+}
+
+// call calls the handler, converting a panic or a timeout into an error.
+// A handler with a timeout runs on a new goroutine started by spawn, it fails with ErrClosing
+// if spawn refuses to start it.
+func (h *changeHandler[T]) call(spawn func(func()) bool, old, new T) error {
+	if h.opts.timeout <= 0 {
+		return h.callSafe(old, new)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.opts.timeout)
+	defer cancel()
+	// buffered, so the handler goroutine can finish after the timeout
+	done := make(chan error, 1)
+	if !spawn(func() {
+		done <- h.callSafe(old, new)
+	}) {
+		return ErrClosing
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%w after %s", ErrHandlerTimeout, h.opts.timeout)
+	}
+}
+
+// callSafe calls the handler, recovering from a panic.
+func (h *changeHandler[T]) callSafe(old, new T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+		}
+	}()
+	return h.fn(old, new)
+}
+
+// changeHandlers is a list of OnChange handlers ordered by priority.
+type changeHandlers[T any] struct {
+	mu       sync.Mutex
+	lastID   uint64
+	handlers []*changeHandler[T]
+	// spawn runs the handlers with a timeout on a new goroutine, the go statement if nil
+	spawn func(func()) bool
+}
+
+// add registers the handler and returns the function that removes it.
+func (hs *changeHandlers[T]) add(fn func(old, new T) error, opts []OnChangeOpt) func() {
+	var o onChangeOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.lastID++
+	h := &changeHandler[T]{id: hs.lastID, fn: fn, opts: o}
+	// keep the handlers sorted, a stable insert keeps the registration order within a priority
+	i, _ := slices.BinarySearchFunc(hs.handlers, h, func(a, b *changeHandler[T]) int {
+		return cmp.Or(cmp.Compare(a.opts.priority, b.opts.priority), cmp.Compare(a.id, b.id))
+	})
+	hs.handlers = slices.Insert(hs.handlers, i, h)
+	return func() {
+		hs.mu.Lock()
+		defer hs.mu.Unlock()
+		hs.handlers = slices.DeleteFunc(hs.handlers, func(e *changeHandler[T]) bool { return e == h })
+	}
+}
+
+// list returns a copy of the handlers.
+func (hs *changeHandlers[T]) list() []*changeHandler[T] {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return slices.Clone(hs.handlers)
+}
+
+// notify calls the handlers in order and reports whether the change must be rolled back,
+// i.e. a handler with ErrorPolicyRollback failed. In that case the handlers called before it
+// already got the change back from new to old.
+func (hs *changeHandlers[T]) notify(old, new T, onError func(error)) bool {
+	handlers := hs.list()
+	spawn := hs.spawn
+	if spawn == nil {
+		spawn = goSpawn
+	}
+	for i, h := range handlers {
+		err := h.call(spawn, old, new)
+		if err == nil {
+			continue
+		}
+		onError(err)
+		if h.opts.policy != ErrorPolicyRollback {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if err := handlers[j].call(spawn, new, old); err != nil {
+				onError(fmt.Errorf("failed to roll back: %w", err))
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChangeHandlersOrder(t *testing.T) {
+	var hs changeHandlers[int]
+	var calls []string
+	handler := func(name string) func(int, int) error {
+		return func(int, int) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	hs.add(handler("b"), nil)
+	hs.add(handler("c"), nil)
+	hs.add(handler("a"), []OnChangeOpt{WithHandlerPriority(-1)})
+	remove := hs.add(handler("removed"), nil)
+	hs.add(handler("d"), []OnChangeOpt{WithHandlerPriority(1)})
+	remove()
+	hs.notify(1, 2, func(err error) { t.Error(err) })
+	if expected := []string{"a", "b", "c", "d"}; !slices.Equal(calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+}
+
+func TestChangeHandlersErrors(t *testing.T) {
+	var hs changeHandlers[int]
+	called := false
+	hs.add(func(int, int) error { panic("boom") }, nil)
+	hs.add(func(int, int) error {
+		time.Sleep(time.Second)
+		return nil
+	}, []OnChangeOpt{WithHandlerTimeout(10 * time.Millisecond)})
+	hs.add(func(int, int) error {
+		called = true
+		return nil
+	}, nil)
+	var errs []error
+	if hs.notify(1, 2, func(err error) { errs = append(errs, err) }) {
+		t.Error("Expected no rollback")
+	}
+	if len(errs) != 2 || !errors.Is(errs[0], ErrHandlerPanic) || !errors.Is(errs[1], ErrHandlerTimeout) {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if !called {
+		t.Error("Expected the handler after the failed ones to be called")
+	}
+}
+
+func TestSubscriptionOnChangeRollback(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	changes := make(chan [2]int, 16)
+	confSubs.OnChange(func(old, new testConfig) error {
+		changes <- [2]int{old.Value, new.Value}
+		return nil
+	})
+	confSubs.OnChange(func(_, new testConfig) error {
+		if new.Value < 0 {
+			return errors.New("negative value")
+		}
+		return nil
+	}, WithErrorPolicy(ErrorPolicyRollback))
+	transport.put("config", `{"value": 2}`)
+	transport.put("config", `{"value": -1}`)
+	transport.put("config", `{"value": 3}`)
+	waitFor(t, func() bool { return confSubs.Get().Value == 3 })
+	var got [][2]int
+	for len(changes) > 0 {
+		got = append(got, <-changes)
+	}
+	// the first handler gets the rolled back change back
+	if expected := [][2]int{{1, 2}, {2, -1}, {-1, 2}, {2, 3}}; !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestSubscriptionOnChangeRollbackRestoresVersion(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	failed := make(chan struct{})
+	confSubs.OnChange(func(testConfig, testConfig) error {
+		close(failed)
+		return errors.New("rejected")
+	}, WithErrorPolicy(ErrorPolicyRollback))
+	version := confSubs.Version()
+	transport.put("config", `{"value": 2}`)
+	<-failed
+	waitFor(t, func() bool { return confSubs.Version() == version })
+	if confSubs.Get().Value != 1 || confSubs.LastUpdate().Revision != 1 {
+		t.Errorf("unexpected value %+v after rollback", confSubs.Get())
+	}
+}
+
+func TestSubscriptionOnChangeRollbackPublished(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	seen := make(chan struct{})
+	confSubs.OnChange(func(_, new testConfig) error {
+		if new.Value == 2 {
+			<-seen
+			return errors.New("rejected")
+		}
+		return nil
+	}, WithErrorPolicy(ErrorPolicyRollback))
+	updates := confSubs.GetUpdates()
+	transport.put("config", `{"value": 2}`)
+	// the new value is published before the handler rolls it back
+	if val := <-updates; val.Value != 2 {
+		t.Errorf("Expected 2, got %d", val.Value)
+	}
+	close(seen)
+	if val := <-updates; val.Value != 1 {
+		t.Errorf("Expected 1 after rollback, got %d", val.Value)
+	}
+}
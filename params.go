@@ -0,0 +1,31 @@
+package sbc
+
+import (
+	"context"
+	"maps"
+)
+
+// KeyParams are the parameters of a subscription instance, like a tenant ID, a shard or a region.
+// They are passed to the key builder through the subscription context, so one Subscriber can open
+// subscriptions for many keys, see Subscriber.SubscribeWithParams.
+type KeyParams map[string]string
+
+// keyParamsKey is the context key of the KeyParams.
+type keyParamsKey struct{}
+
+// ContextWithKeyParams returns a copy of the context that carries the given KeyParams.
+// The params are merged with the params already stored in the context, the new ones take precedence.
+func ContextWithKeyParams(ctx context.Context, params KeyParams) context.Context {
+	merged := KeyParamsFromContext(ctx)
+	maps.Copy(merged, params)
+	return context.WithValue(ctx, keyParamsKey{}, merged)
+}
+
+// KeyParamsFromContext returns a copy of the KeyParams stored in the context, or empty KeyParams.
+func KeyParamsFromContext(ctx context.Context) KeyParams {
+	params, _ := ctx.Value(keyParamsKey{}).(KeyParams)
+	if params == nil {
+		return KeyParams{}
+	}
+	return maps.Clone(params)
+}
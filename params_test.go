@@ -0,0 +1,27 @@
+package sbc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKeyParamsFromContextEmpty(t *testing.T) {
+	params := KeyParamsFromContext(context.Background())
+	if params == nil || len(params) != 0 {
+		t.Errorf("Expected empty params, got %v", params)
+	}
+}
+
+func TestContextWithKeyParamsMerges(t *testing.T) {
+	ctx := ContextWithKeyParams(context.Background(), KeyParams{"tenant": "7", "region": "eu"})
+	ctx = ContextWithKeyParams(ctx, KeyParams{"tenant": "42"})
+	params := KeyParamsFromContext(ctx)
+	if params["tenant"] != "42" || params["region"] != "eu" {
+		t.Errorf("unexpected params %v", params)
+	}
+	// the returned params are a copy
+	params["tenant"] = "0"
+	if KeyParamsFromContext(ctx)["tenant"] != "42" {
+		t.Error("params stored in the context were modified")
+	}
+}
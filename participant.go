@@ -0,0 +1,154 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrParticipantPanic is an error that is returned when a participant panics in Prepare, Commit or Abort.
+var ErrParticipantPanic = errors.New("participant panicked")
+
+// Participant takes part in applying a new value of a subscription with a two-phase protocol.
+// First, all the participants prepare the new value, e.g. validate it or open a new DB pool.
+// Only if all of them succeed, the subscription stores the new value, calls the OnChange handlers
+// and then Commit on all of them. If any of them fails, the participants that already prepared the value
+// are aborted, in reverse order, and the subscription keeps the old value. The same happens when an OnChange
+// handler rolls the change back, see ErrorPolicyRollback: all the participants are aborted instead of committed.
+type Participant[T any] interface {
+
+	// Prepare validates and prepares the new value, e.g. opens the resources it needs.
+	// An error vetoes the new value.
+	Prepare(ctx context.Context, old, new T) error
+
+	// Commit switches to the prepared new value, e.g. swaps the DB pool and closes the old one.
+	Commit(ctx context.Context, old, new T)
+
+	// Abort releases what Prepare allocated for the new value, that is not going to be applied.
+	Abort(ctx context.Context, old, new T)
+}
+
+// ParticipantFuncs is a Participant defined by functions, any of them can be nil.
+type ParticipantFuncs[T any] struct {
+	PrepareFunc func(ctx context.Context, old, new T) error
+	CommitFunc  func(ctx context.Context, old, new T)
+	AbortFunc   func(ctx context.Context, old, new T)
+}
+
+// Prepare calls PrepareFunc if it is set.
+func (p ParticipantFuncs[T]) Prepare(ctx context.Context, old, new T) error {
+	if p.PrepareFunc == nil {
+		return nil
+	}
+	return p.PrepareFunc(ctx, old, new)
+}
+
+// Commit calls CommitFunc if it is set.
+func (p ParticipantFuncs[T]) Commit(ctx context.Context, old, new T) {
+	if p.CommitFunc != nil {
+		p.CommitFunc(ctx, old, new)
+	}
+}
+
+// Abort calls AbortFunc if it is set.
+func (p ParticipantFuncs[T]) Abort(ctx context.Context, old, new T) {
+	if p.AbortFunc != nil {
+		p.AbortFunc(ctx, old, new)
+	}
+}
+
+// PrepareError is an error that is returned when a participant rejects a new value.
+type PrepareError struct {
+
+	// Version is the version of the rejected value.
+	Version Version
+
+	// Err is the error returned by the participant.
+	Err error
+}
+
+// Error returns the error message.
+func (e *PrepareError) Error() string {
+	return fmt.Sprintf("value %s rejected: %v", e.Version, e.Err)
+}
+
+// Unwrap returns the error returned by the participant.
+func (e *PrepareError) Unwrap() error {
+	return e.Err
+}
+
+// participant is a registered Participant.
+type participant[T any] struct {
+	Participant[T]
+}
+
+// participants is a list of participants in the order of registration.
+type participants[T any] struct {
+	mu   sync.Mutex
+	list []*participant[T]
+}
+
+// add registers the participant and returns the function that removes it.
+func (ps *participants[T]) add(p Participant[T]) func() {
+	entry := &participant[T]{p}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.list = append(ps.list, entry)
+	return func() {
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+		ps.list = slices.DeleteFunc(ps.list, func(e *participant[T]) bool { return e == entry })
+	}
+}
+
+// prepare prepares the new value with all the participants. If one of them fails, the ones that already
+// prepared the value are aborted and the error is returned. Otherwise, exactly one of the returned functions
+// must be called: commit commits the new value with all the participants, abort aborts it with all of them.
+// Both return the panics of the participants as errors, a panic does not stop the other participants.
+func (ps *participants[T]) prepare(ctx context.Context, old, new T) (commit, abort func() error, err error) {
+	ps.mu.Lock()
+	list := slices.Clone(ps.list)
+	ps.mu.Unlock()
+	for i, p := range list {
+		if err := safeCall(func() error { return p.Prepare(ctx, old, new) }); err != nil {
+			abortErr := abortAll(ctx, list[:i], old, new)
+			return nil, nil, errors.Join(fmt.Errorf("participant %d: %w", i, err), abortErr)
+		}
+	}
+	commit = func() error {
+		var errs []error
+		for i, p := range list {
+			if err := safeCall(func() error { p.Commit(ctx, old, new); return nil }); err != nil {
+				errs = append(errs, fmt.Errorf("participant %d: %w", i, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	abort = func() error {
+		return abortAll(ctx, list, old, new)
+	}
+	return commit, abort, nil
+}
+
+// abortAll aborts the new value with the participants in reverse order.
+func abortAll[T any](ctx context.Context, list []*participant[T], old, new T) error {
+	var errs []error
+	for i := len(list) - 1; i >= 0; i-- {
+		if err := safeCall(func() error { list[i].Abort(ctx, old, new); return nil }); err != nil {
+			errs = append(errs, fmt.Errorf("participant %d: failed to abort: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// safeCall calls a method of a participant, recovering from a panic.
+func safeCall(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrParticipantPanic, r)
+		}
+	}()
+	return fn()
+}
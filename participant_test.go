@@ -0,0 +1,156 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// recordingParticipant records the calls of the two-phase protocol and rejects the given values.
+type recordingParticipant struct {
+	name   string
+	reject int
+	mu     *sync.Mutex
+	calls  *[]string
+}
+
+func (p recordingParticipant) record(call string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*p.calls = append(*p.calls, p.name+"."+call)
+}
+
+func (p recordingParticipant) Prepare(_ context.Context, _, new testConfig) error {
+	p.record("prepare")
+	if new.Value == p.reject {
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func (p recordingParticipant) Commit(context.Context, testConfig, testConfig) {
+	p.record("commit")
+}
+
+func (p recordingParticipant) Abort(context.Context, testConfig, testConfig) {
+	p.record("abort")
+}
+
+func TestSubscriptionParticipants(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	var mu sync.Mutex
+	var calls []string
+	confSubs.AddParticipant(recordingParticipant{name: "a", reject: -1, mu: &mu, calls: &calls})
+	confSubs.AddParticipant(recordingParticipant{name: "b", reject: 3, mu: &mu, calls: &calls})
+	confSubs.AddParticipant(recordingParticipant{name: "c", reject: -1, mu: &mu, calls: &calls})
+
+	transport.put("config", `{"value": 2}`)
+	waitFor(t, func() bool { return confSubs.Get().Value == 2 })
+	// the rejected value is not applied and reported
+	transport.put("config", `{"value": 3}`)
+	waitFor(t, func() bool { return confSubs.ApplyError() != nil })
+	var prepareErr *PrepareError
+	if err := confSubs.ApplyError(); !errors.As(err, &prepareErr) || prepareErr.Version.Revision != 3 {
+		t.Errorf("unexpected error %v", err)
+	}
+	if confSubs.Get().Value != 2 || confSubs.Version().Revision != 2 {
+		t.Errorf("unexpected value %+v", confSubs.Get())
+	}
+	transport.put("config", `{"value": 4}`)
+	waitFor(t, func() bool { return confSubs.Get().Value == 4 })
+	if confSubs.ApplyError() != nil {
+		t.Errorf("unexpected error %v", confSubs.ApplyError())
+	}
+	// the participants commit after the value is stored
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(calls) == 15
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{
+		"a.prepare", "b.prepare", "c.prepare", "a.commit", "b.commit", "c.commit",
+		"a.prepare", "b.prepare", "a.abort",
+		"a.prepare", "b.prepare", "c.prepare", "a.commit", "b.commit", "c.commit",
+	}
+	if !slices.Equal(calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+}
+
+func TestParticipantsPanic(t *testing.T) {
+	var ps participants[int]
+	aborted := false
+	ps.add(ParticipantFuncs[int]{AbortFunc: func(context.Context, int, int) { aborted = true }})
+	remove := ps.add(ParticipantFuncs[int]{PrepareFunc: func(context.Context, int, int) error { panic("boom") }})
+	if _, _, err := ps.prepare(context.Background(), 1, 2); !errors.Is(err, ErrParticipantPanic) || !aborted {
+		t.Errorf("unexpected error %v", err)
+	}
+	remove()
+	committed := false
+	ps.add(ParticipantFuncs[int]{
+		CommitFunc: func(context.Context, int, int) { panic("boom") },
+		AbortFunc:  func(context.Context, int, int) { panic("boom") },
+	})
+	ps.add(ParticipantFuncs[int]{CommitFunc: func(context.Context, int, int) { committed = true }})
+	commit, abort, err := ps.prepare(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a panic does not stop the other participants
+	if err := commit(); !errors.Is(err, ErrParticipantPanic) || !committed {
+		t.Errorf("unexpected error %v", err)
+	}
+	aborted = false
+	if err := abort(); !errors.Is(err, ErrParticipantPanic) || !aborted {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSubscriptionParticipantsRollback(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	var mu sync.Mutex
+	var calls []string
+	confSubs.AddParticipant(recordingParticipant{name: "a", reject: -1, mu: &mu, calls: &calls})
+	confSubs.OnChange(func(_, new testConfig) error {
+		if new.Value == 2 {
+			return errors.New("rejected")
+		}
+		return nil
+	}, WithErrorPolicy(ErrorPolicyRollback))
+
+	// the handler rolls the change back, so the participant is aborted instead of committed
+	transport.put("config", `{"value": 2}`)
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(calls) == 2
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if expected := []string{"a.prepare", "a.abort"}; !slices.Equal(calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+	if confSubs.Get().Value != 1 {
+		t.Errorf("unexpected value %+v", confSubs.Get())
+	}
+}
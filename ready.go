@@ -0,0 +1,59 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNotReady is an error that is returned when a subscription does not get its initial value in time.
+var ErrNotReady = errors.New("not ready")
+
+// Readier is implemented by the values that get ready asynchronously, like a Subscription started
+// by Subscriber.SubscribeAsync, or a Manager.
+type Readier interface {
+
+	// Ready reports whether the value is ready.
+	Ready() bool
+
+	// WaitReady waits until the value is ready, it fails when the context is done first.
+	WaitReady(ctx context.Context) error
+}
+
+var (
+	_ Readier = (*Subscription[any])(nil)
+	_ Readier = (*Manager)(nil)
+	_ Readier = (*Managed[any])(nil)
+)
+
+// WaitAllReady waits until all the values are ready, e.g. all the configs a service needs to serve.
+// It fails with the errors of the values that are not ready when the context is done.
+//
+//	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+//	defer cancel()
+//	if err := sbc.WaitAllReady(ctx, db, limits); err != nil {
+//		log.Fatal(err)
+//	}
+func WaitAllReady(ctx context.Context, rs ...Readier) error {
+	errs := make([]error, len(rs))
+	var wg sync.WaitGroup
+	for i, r := range rs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.WaitReady(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// AllReady reports whether all the values are ready.
+func AllReady(rs ...Readier) bool {
+	for _, r := range rs {
+		if !r.Ready() {
+			return false
+		}
+	}
+	return true
+}
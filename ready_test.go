@@ -0,0 +1,234 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscriberSubscribeAsync(t *testing.T) {
+	transport := newMockTransport(nil)
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }))
+	confSubs, err := sub.SubscribeAsync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	if confSubs.Ready() || confSubs.Get().Value != 0 {
+		t.Fatalf("unexpected value %+v", confSubs.Get())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = confSubs.WaitReady(ctx)
+	if !errors.Is(err, ErrNotReady) || !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errMockNotFound) {
+		t.Errorf("unexpected error %v", err)
+	}
+	// the key is created later and received by the watch
+	transport.put("config", `{"value": 1}`)
+	if err := confSubs.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !confSubs.Ready() || confSubs.Get().Value != 1 {
+		t.Errorf("unexpected value %+v", confSubs.Get())
+	}
+}
+
+// lateTransport is a transport without updates whose key gets a value after the given number of calls.
+type lateTransport struct {
+	legacyTransport
+	calls atomic.Int32
+	after int32
+}
+
+func (l *lateTransport) CurrentUpdate(_ context.Context, key string) (Update, error) {
+	if l.calls.Add(1) <= l.after {
+		return Update{}, errMockNotFound
+	}
+	return Update{Key: key, Value: []byte(`{"value": 7}`)}, nil
+}
+
+func (l *lateTransport) WatchUpdates(context.Context, string) (<-chan Update, error) {
+	return nil, nil
+}
+
+func TestSubscriberSubscribeAsyncRetries(t *testing.T) {
+	transport := &lateTransport{after: 2}
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithRetryInterval(time.Millisecond))
+	confSubs, err := sub.SubscribeAsync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := WaitAllReady(ctx, confSubs); err != nil {
+		t.Fatal(err)
+	}
+	if confSubs.Get().Value != 7 || transport.calls.Load() != 3 {
+		t.Errorf("unexpected value %+v after %d calls", confSubs.Get(), transport.calls.Load())
+	}
+}
+
+func TestSubscriptionWaitReadyUnsubscribed(t *testing.T) {
+	sub := NewSubscriber[testConfig](newMockTransport(nil), KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }))
+	confSubs, err := sub.SubscribeAsync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	confSubs.Unsubscribe()
+	if err := confSubs.WaitReady(context.Background()); !errors.Is(err, ErrNotReady) || !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestWaitAllReady(t *testing.T) {
+	transport := newMockTransport(map[string]string{"a": `{"value": 1}`})
+	a := subscribeTest(t, transport, "a")
+	b, err := newTestSubscriber(transport, "b").SubscribeAsync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Unsubscribe()
+	if AllReady(a, b) {
+		t.Error("Expected b not to be ready")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := WaitAllReady(ctx, a, b); !errors.Is(err, ErrNotReady) {
+		t.Errorf("unexpected error %v", err)
+	}
+	transport.put("b", `{"value": 2}`)
+	if err := WaitAllReady(context.Background(), a, b); err != nil || !AllReady(a, b) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestManagerAsyncStart(t *testing.T) {
+	transport := newMockTransport(map[string]string{"a": `{"value": 1}`})
+	m := NewManager()
+	_, _ = Manage(m, "a", newTestSubscriber(transport, "a"))
+	b, _ := Manage(m, "b", newTestSubscriber(transport, "b"), WithAsyncStart())
+	_, _ = Manage(m, "c", newTestSubscriber(transport, "c"), WithAsyncStart(), AsOptional())
+	if err := m.WaitReady(context.Background()); !errors.Is(err, ErrNotReady) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown(context.Background())
+	if m.Ready() || b.Status().State != StateStarting {
+		t.Errorf("unexpected statuses %+v", m.Status())
+	}
+	transport.put("b", `{"value": 2}`)
+	// the optional config is not waited for
+	if err := m.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Ready() || b.Subscription().Get().Value != 2 {
+		t.Errorf("unexpected statuses %+v", m.Status())
+	}
+}
+
+// flakyTransport is a mockTransport whose calls fail while their counters are positive:
+// WatchUpdates of the "legacy" key and CurrentUpdate of any key.
+type flakyTransport struct {
+	*mockTransport
+	watchFails   atomic.Int32
+	currentFails atomic.Int32
+}
+
+func (f *flakyTransport) CurrentUpdate(ctx context.Context, key string) (Update, error) {
+	if f.currentFails.Add(-1) >= 0 {
+		return Update{}, errors.New("unavailable")
+	}
+	return f.mockTransport.CurrentUpdate(ctx, key)
+}
+
+func (f *flakyTransport) WatchUpdates(ctx context.Context, key string) (<-chan Update, error) {
+	if key == "legacy" && f.watchFails.Add(-1) >= 0 {
+		return nil, errors.New("unavailable")
+	}
+	return f.mockTransport.WatchUpdates(ctx, key)
+}
+
+func TestSubscriberSubscribeAsyncWatchRetries(t *testing.T) {
+	transport := &flakyTransport{mockTransport: newMockTransport(map[string]string{"config": `{"value": 1}`})}
+	transport.watchFails.Store(3)
+	sub := NewSubscriber[testConfig](transport, aliasKeys{"config", "legacy"}, WithRetryInterval(time.Millisecond))
+	confSubs, err := sub.SubscribeAsync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	if err := confSubs.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// the watches of the primary key started by the failed attempts are stopped
+	waitFor(t, func() bool {
+		transport.mu.Lock()
+		defer transport.mu.Unlock()
+		return transport.watchFails.Load() < 0 && len(transport.watchers["config"]) == 1
+	})
+}
+
+func TestSubscriberSubscribeAsyncAliasFirst(t *testing.T) {
+	transport := &flakyTransport{mockTransport: newMockTransport(map[string]string{"config": `{"value": 1}`})}
+	// the initial load fails, then an update of the alias arrives first
+	transport.currentFails.Store(2)
+	sub := NewSubscriber[testConfig](transport, aliasKeys{"config", "legacy"}, WithRetryInterval(time.Hour))
+	confSubs, err := sub.SubscribeAsync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	waitFor(t, func() bool { return confSubs.loadErr.Load() != nil })
+	transport.put("legacy", `{"value": 2}`)
+	if err := confSubs.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if confSubs.Key() != "config" || confSubs.Get().Value != 1 {
+		t.Errorf("unexpected value %+v from '%s'", confSubs.Get(), confSubs.Key())
+	}
+}
+
+func TestSubscriberSubscribeAsyncRollbackFirst(t *testing.T) {
+	transport := newMockTransport(nil)
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	confSubs, err := sub.SubscribeAsync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	var calls atomic.Int32
+	confSubs.OnChange(func(_, new testConfig) error {
+		calls.Add(1)
+		if new.Value == 1 {
+			return errors.New("rejected")
+		}
+		return nil
+	}, WithErrorPolicy(ErrorPolicyRollback))
+	// the rejected first value does not make the subscription ready
+	transport.put("config", `{"value": 1}`)
+	waitFor(t, func() bool { return calls.Load() > 0 })
+	if confSubs.Ready() || confSubs.Get().Value != 0 || confSubs.Version() != (Version{}) {
+		t.Errorf("unexpected value %+v, %s", confSubs.Get(), confSubs.Version())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := confSubs.WaitReady(ctx); !errors.Is(err, ErrNotReady) {
+		t.Errorf("unexpected error %v", err)
+	}
+	transport.put("config", `{"value": 2}`)
+	if err := confSubs.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if confSubs.Get().Value != 2 {
+		t.Errorf("unexpected value %+v", confSubs.Get())
+	}
+}
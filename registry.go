@@ -0,0 +1,131 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+var (
+	// ErrAlreadyRegistered is an error that is returned when a name or a subscription is already registered.
+	ErrAlreadyRegistered = errors.New("already registered")
+
+	// ErrNotRegistered is an error that is returned when a name is not registered.
+	ErrNotRegistered = errors.New("not registered")
+)
+
+// Registry is a set of named subscriptions of a process, that can be read at once with Snapshot.
+// The registered subscriptions store their changes under the registry lock, so a Snapshot captures
+// the values of all of them at the same logical moment: it never sees one config from before
+// a rollout and another one from after it.
+type Registry struct {
+	// mu is write-locked by the registered subscriptions storing a change and read-locked by Snapshot
+	mu      sync.RWMutex
+	entries map[string]registryEntry
+}
+
+// registryEntry is a registered subscription.
+type registryEntry struct {
+	get        func() (any, Version)
+	unregister func()
+}
+
+// NewRegistry creates a new empty registry
+func NewRegistry() *Registry {
+	return &Registry{entries: map[string]registryEntry{}}
+}
+
+// Register registers the subscription in the registry under the name. A subscription can be registered
+// in one registry only, and the name must be unique in the registry.
+func Register[T any](r *Registry, name string, sub *Subscription[T]) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.entries[name]; ok {
+		return fmt.Errorf("name '%s': %w", name, ErrAlreadyRegistered)
+	}
+	if !sub.registry.CompareAndSwap(nil, r) {
+		return fmt.Errorf("subscription of '%s': %w", name, ErrAlreadyRegistered)
+	}
+	r.entries[name] = registryEntry{
+		get: func() (any, Version) {
+			val, version := sub.GetWithVersion()
+			return val, version
+		},
+		unregister: func() { sub.registry.Store(nil) },
+	}
+	return nil
+}
+
+// Unregister removes the subscription registered under the name from the registry.
+func (r *Registry) Unregister(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.entries[name]
+	if !ok {
+		return fmt.Errorf("name '%s': %w", name, ErrNotRegistered)
+	}
+	entry.unregister()
+	delete(r.entries, name)
+	return nil
+}
+
+// Names returns the sorted names of the registered subscriptions.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.entries))
+}
+
+// Snapshot returns the current values of all the registered subscriptions, captured atomically.
+func (r *Registry) Snapshot() Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s := Snapshot{values: make(map[string]any, len(r.entries)), versions: make(map[string]Version, len(r.entries))}
+	for name, entry := range r.entries {
+		s.values[name], s.versions[name] = entry.get()
+	}
+	return s
+}
+
+// Snapshot is a coherent view of the values of the subscriptions of a Registry at the same logical moment.
+// Use SnapshotValue to get a value, and ContextWithSnapshot to use one Snapshot for a whole request.
+type Snapshot struct {
+	values   map[string]any
+	versions map[string]Version
+}
+
+// SnapshotValue returns the value of the subscription registered under the name in the snapshot.
+// It returns false if there is no such subscription, or its value is not of type T.
+func SnapshotValue[T any](s Snapshot, name string) (T, bool) {
+	val, ok := s.values[name].(T)
+	return val, ok
+}
+
+// Version returns the version of the value of the subscription registered under the name in the snapshot.
+func (s Snapshot) Version(name string) (Version, bool) {
+	version, ok := s.versions[name]
+	return version, ok
+}
+
+// Names returns the sorted names of the subscriptions in the snapshot.
+func (s Snapshot) Names() []string {
+	return slices.Sorted(maps.Keys(s.values))
+}
+
+// snapshotKey is the context key of the Snapshot.
+type snapshotKey struct{}
+
+// ContextWithSnapshot returns a copy of ctx that carries the snapshot, e.g. to give a whole request
+// one coherent view of the configs.
+func ContextWithSnapshot(ctx context.Context, s Snapshot) context.Context {
+	return context.WithValue(ctx, snapshotKey{}, s)
+}
+
+// SnapshotFromContext returns the snapshot stored in ctx by ContextWithSnapshot.
+func SnapshotFromContext(ctx context.Context) (Snapshot, bool) {
+	s, ok := ctx.Value(snapshotKey{}).(Snapshot)
+	return s, ok
+}
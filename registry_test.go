@@ -0,0 +1,108 @@
+package sbc
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestRegistrySnapshot(t *testing.T) {
+	transport := newMockTransport(map[string]string{"a": `{"value": 1}`, "b": `{"value": 2}`})
+	a, b := subscribeTest(t, transport, "a"), subscribeTest(t, transport, "b")
+	r := NewRegistry()
+	if err := Register(r, "a", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(r, "b", b); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(r, "a", b); !errors.Is(err, ErrAlreadyRegistered) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := Register(NewRegistry(), "a", a); !errors.Is(err, ErrAlreadyRegistered) {
+		t.Errorf("unexpected error %v", err)
+	}
+	s := r.Snapshot()
+	transport.put("a", `{"value": 10}`)
+	waitFor(t, func() bool { return a.Get().Value == 10 })
+	// the snapshot does not change
+	if val, ok := SnapshotValue[testConfig](s, "a"); !ok || val.Value != 1 {
+		t.Errorf("unexpected value %+v", val)
+	}
+	if version, ok := s.Version("b"); !ok || version.Key != "b" {
+		t.Errorf("unexpected version %+v", version)
+	}
+	if _, ok := SnapshotValue[int](s, "a"); ok {
+		t.Error("Expected a type mismatch")
+	}
+	if names := s.Names(); !slices.Equal(names, []string{"a", "b"}) {
+		t.Errorf("unexpected names %v", names)
+	}
+	ctx := ContextWithSnapshot(context.Background(), r.Snapshot())
+	s, ok := SnapshotFromContext(ctx)
+	if val, _ := SnapshotValue[testConfig](s, "a"); !ok || val.Value != 10 {
+		t.Errorf("unexpected value %+v", val)
+	}
+	if err := r.Unregister("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Unregister("a"); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if names := r.Names(); !slices.Equal(names, []string{"b"}) {
+		t.Errorf("unexpected names %v", names)
+	}
+}
+
+func TestRegistrySnapshotConcurrent(t *testing.T) {
+	transport := newMockTransport(map[string]string{"a": `{"value": 0}`, "b": `{"value": 0}`})
+	a, b := subscribeTest(t, transport, "a"), subscribeTest(t, transport, "b")
+	r := NewRegistry()
+	_ = Register(r, "a", a)
+	_ = Register(r, "b", b)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					runtime.Gosched()
+				}
+				s := r.Snapshot()
+				valA, _ := SnapshotValue[testConfig](s, "a")
+				valB, _ := SnapshotValue[testConfig](s, "b")
+				versionA, _ := s.Version("a")
+				versionB, _ := s.Version("b")
+				if valA.Value == 0 || valB.Value == 0 {
+					continue
+				}
+				// a is always written first, so a coherent cut sees b at the value of a or one behind,
+				// and the versions of a and b are the adjacent revisions of the same pair
+				switch {
+				case valA.Value == valB.Value && versionB.Revision == versionA.Revision+1:
+				case valA.Value == valB.Value+1 && versionA.Revision == versionB.Revision+1:
+				default:
+					t.Errorf("incoherent snapshot a=%d (%s), b=%d (%s)", valA.Value, versionA, valB.Value, versionB)
+					return
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 20; i++ {
+		transport.put("a", `{"value": `+strconv.Itoa(i)+`}`)
+		waitFor(t, func() bool { return a.Get().Value == i })
+		transport.put("b", `{"value": `+strconv.Itoa(i)+`}`)
+		waitFor(t, func() bool { return b.Get().Value == i })
+	}
+	close(done)
+	wg.Wait()
+}
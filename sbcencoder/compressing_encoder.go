@@ -0,0 +1,197 @@
+package sbcencoder
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is an algorithm used by the CompressingEncoder.
+type Compression int
+
+const (
+	// CompressionGzip compresses payloads with gzip.
+	CompressionGzip Compression = iota
+
+	// CompressionZstd compresses payloads with zstd.
+	CompressionZstd
+)
+
+// defaultCompressionThreshold is the default payload size in bytes below which payloads are stored uncompressed.
+const defaultCompressionThreshold = 1024
+
+// defaultMaxDecompressedSize is the default limit of a decompressed payload size in bytes.
+const defaultMaxDecompressedSize = 64 << 20
+
+var (
+	// gzipMagic is the header of every gzip stream.
+	gzipMagic = []byte{0x1f, 0x8b}
+
+	// zstdMagic is the header of every zstd frame.
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ErrDecompressedTooLarge is an error that is returned when a decompressed payload exceeds the configured limit.
+var ErrDecompressedTooLarge = errors.New("decompressed payload is too large")
+
+// CompressingEncoder is an encoder decorator that compresses the payloads produced by the wrapped encoder.
+//
+// Payloads smaller than the threshold are stored as is. On Decode the compression is detected by the magic bytes
+// of the payload, so uncompressed legacy values and values compressed with any supported algorithm are accepted.
+type CompressingEncoder struct {
+	inner         Encoder
+	compression   Compression
+	threshold     int
+	maxDecodeSize int64
+
+	// the zstd encoder and decoder are created on first use, EncodeAll and DecodeAll are safe for concurrent use.
+	// They are never closed, so they run with a concurrency of 1: no background goroutine and a single set of buffers.
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+}
+
+// NewCompressingEncoder creates a new CompressingEncoder that wraps the given encoder.
+// By default, payloads of 1KiB and more are compressed with gzip.
+func NewCompressingEncoder(inner Encoder, opts ...CompressingEncoderOpt) *CompressingEncoder {
+	c := &CompressingEncoder{
+		inner:         inner,
+		compression:   CompressionGzip,
+		threshold:     defaultCompressionThreshold,
+		maxDecodeSize: defaultMaxDecompressedSize,
+	}
+	// Apply options
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Encode encodes the given value v with the wrapped encoder and compresses the result.
+func (c *CompressingEncoder) Encode(v any) ([]byte, error) {
+	b, err := c.inner.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < c.threshold {
+		return b, nil
+	}
+	switch c.compression {
+	case CompressionZstd:
+		if err := c.initZstd(); err != nil {
+			return nil, err
+		}
+		return c.zstdEncoder.EncodeAll(b, nil), nil
+	default:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(b); err != nil {
+			return nil, fmt.Errorf("failed to compress value: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress value: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// Decode decompresses the byte slice if it is compressed and decodes it with the wrapped encoder.
+func (c *CompressingEncoder) Decode(data []byte, v any) error {
+	return c.DecodeWithHeaders(data, nil, v)
+}
+
+// DecodeWithHeaders decompresses the byte slice if it is compressed and decodes it with the wrapped encoder,
+// passing the headers to it if it is a HeaderDecoder.
+func (c *CompressingEncoder) DecodeWithHeaders(data []byte, headers map[string]string, v any) error {
+	b, err := c.decompress(data)
+	if err != nil {
+		return fmt.Errorf("failed to decompress value: %w", err)
+	}
+	return decodeWithHeaders(c.inner, b, headers, v)
+}
+
+// decompress detects the compression of the payload and decompresses it.
+func (c *CompressingEncoder) decompress(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, zstdMagic):
+		if err := c.initZstd(); err != nil {
+			return nil, err
+		}
+		// the decoder stops as soon as the payload exceeds the limit, before allocating more
+		b, err := c.zstdDecoder.DecodeAll(data, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			return nil, fmt.Errorf("%w: %w", ErrDecompressedTooLarge, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	case bytes.HasPrefix(data, gzipMagic):
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		// read one byte over the limit to detect too large payloads
+		b, err := io.ReadAll(io.LimitReader(r, c.maxDecodeSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(b)) > c.maxDecodeSize {
+			return nil, ErrDecompressedTooLarge
+		}
+		return b, nil
+	default:
+		return data, nil
+	}
+}
+
+// initZstd creates the zstd encoder and decoder on first use. The decoder is limited
+// to the maximum decompressed size.
+func (c *CompressingEncoder) initZstd() error {
+	c.zstdOnce.Do(func() {
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			c.zstdErr = fmt.Errorf("failed to create zstd encoder: %w", err)
+			return
+		}
+		decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(max(c.maxDecodeSize, 1))))
+		if err != nil {
+			c.zstdErr = fmt.Errorf("failed to create zstd decoder: %w", err)
+			return
+		}
+		c.zstdEncoder, c.zstdDecoder = encoder, decoder
+	})
+	return c.zstdErr
+}
+
+// CompressingEncoderOpt is a function type that modifies the properties of a CompressingEncoder.
+type CompressingEncoderOpt func(*CompressingEncoder)
+
+// WithCompression sets the algorithm used to compress payloads on Encode.
+func WithCompression(compression Compression) CompressingEncoderOpt {
+	return func(c *CompressingEncoder) {
+		c.compression = compression
+	}
+}
+
+// WithCompressionThreshold sets the payload size in bytes below which payloads are not compressed.
+func WithCompressionThreshold(threshold int) CompressingEncoderOpt {
+	return func(c *CompressingEncoder) {
+		c.threshold = threshold
+	}
+}
+
+// WithMaxDecompressedSize sets the maximum size in bytes of a decompressed payload.
+// It protects subscribers against decompression bombs.
+func WithMaxDecompressedSize(size int64) CompressingEncoderOpt {
+	return func(c *CompressingEncoder) {
+		c.maxDecodeSize = size
+	}
+}
@@ -0,0 +1,93 @@
+package sbcencoder_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbcencoder"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressingEncoderBelowThreshold(t *testing.T) {
+	encoder := sbcencoder.NewCompressingEncoder(sbcencoder.NewJsonEncoder())
+	data, err := encoder.Encode(map[string]string{"key": "value"})
+	if err != nil || string(data) != `{"key":"value"}` {
+		t.Errorf("unexpected result %q, %v", data, err)
+	}
+}
+
+func TestCompressingEncoderRoundTrip(t *testing.T) {
+	value := map[string]string{"key": strings.Repeat("value", 1000)}
+	for _, compression := range []sbcencoder.Compression{sbcencoder.CompressionGzip, sbcencoder.CompressionZstd} {
+		encoder := sbcencoder.NewCompressingEncoder(sbcencoder.NewJsonEncoder(), sbcencoder.WithCompression(compression))
+		data, err := encoder.Encode(value)
+		if err != nil || len(data) >= 5000 {
+			t.Errorf("compression %d: unexpected result of %d bytes, %v", compression, len(data), err)
+		}
+		var v map[string]string
+		if err := encoder.Decode(data, &v); err != nil || v["key"] != value["key"] {
+			t.Errorf("compression %d: failed to decode, %v", compression, err)
+		}
+	}
+}
+
+func TestCompressingEncoderDecodeUncompressed(t *testing.T) {
+	encoder := sbcencoder.NewCompressingEncoder(sbcencoder.NewJsonEncoder())
+	var v map[string]string
+	err := encoder.Decode([]byte(`{"key":"value"}`), &v)
+	if err != nil || v["key"] != "value" {
+		t.Fail()
+	}
+}
+
+func TestCompressingEncoderDecodeOtherAlgorithm(t *testing.T) {
+	zstd := sbcencoder.NewCompressingEncoder(sbcencoder.NewJsonEncoder(),
+		sbcencoder.WithCompression(sbcencoder.CompressionZstd), sbcencoder.WithCompressionThreshold(0))
+	data, _ := zstd.Encode(map[string]string{"key": "value"})
+	gzip := sbcencoder.NewCompressingEncoder(sbcencoder.NewJsonEncoder())
+	var v map[string]string
+	if err := gzip.Decode(data, &v); err != nil || v["key"] != "value" {
+		t.Fail()
+	}
+}
+
+func TestCompressingEncoderDecodeTooLarge(t *testing.T) {
+	encoder := sbcencoder.NewCompressingEncoder(sbcencoder.NewJsonEncoder(),
+		sbcencoder.WithCompressionThreshold(0), sbcencoder.WithMaxDecompressedSize(16))
+	data, _ := encoder.Encode(map[string]string{"key": strings.Repeat("value", 10)})
+	var v map[string]string
+	if err := encoder.Decode(data, &v); !errors.Is(err, sbcencoder.ErrDecompressedTooLarge) {
+		t.Errorf("Expected ErrDecompressedTooLarge, got %v", err)
+	}
+}
+
+func TestCompressingEncoderDecodeZstdBomb(t *testing.T) {
+	// a streamed frame does not declare its size, the decoder must stop at the limit
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(make([]byte, 8<<20)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	encoder := sbcencoder.NewCompressingEncoder(sbcencoder.NewJsonEncoder(), sbcencoder.WithMaxDecompressedSize(1<<20))
+	var v map[string]string
+	if err := encoder.Decode(buf.Bytes(), &v); !errors.Is(err, sbcencoder.ErrDecompressedTooLarge) {
+		t.Errorf("Expected ErrDecompressedTooLarge, got %v", err)
+	}
+}
+
+func TestCompressingEncoderDecodeCorrupted(t *testing.T) {
+	encoder := sbcencoder.NewCompressingEncoder(sbcencoder.NewJsonEncoder(), sbcencoder.WithCompressionThreshold(0))
+	data, _ := encoder.Encode(map[string]string{"key": "value"})
+	var v map[string]string
+	if err := encoder.Decode(bytes.Clone(data[:len(data)/2]), &v); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,213 @@
+package sbcencoder
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// encryptedMagic starts every payload produced by the EncryptingEncoder.
+var encryptedMagic = []byte("SBCE")
+
+// encryptedVersion is the version of the encrypted envelope format.
+const encryptedVersion = 1
+
+var (
+	// ErrNotEncrypted is an error that is returned when a payload is not an encrypted envelope.
+	ErrNotEncrypted = errors.New("payload is not encrypted")
+
+	// ErrUnknownKey is an error that is returned when a key ID is not present in the keyring.
+	ErrUnknownKey = errors.New("unknown encryption key")
+
+	// ErrInvalidEnvelope is an error that is returned when an envelope is truncated or has an unsupported version.
+	ErrInvalidEnvelope = errors.New("invalid envelope")
+)
+
+// Keyring holds the AES keys used by the EncryptingEncoder.
+//
+// All keys in the keyring can decrypt payloads, only the primary key is used to encrypt them.
+// To rotate a key, add the new key, make it primary once every subscriber has it, and remove the old key
+// once every payload has been re-encrypted. Keyring is safe for concurrent use.
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[string]cipher.AEAD
+	primary string
+}
+
+// NewKeyring creates a new Keyring with the given primary key.
+// The key must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewKeyring(primaryID string, primaryKey []byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	if err := k.Add(primaryID, primaryKey); err != nil {
+		return nil, err
+	}
+	k.primary = primaryID
+	return k, nil
+}
+
+// Add adds a key to the keyring, replacing the key with the same ID.
+func (k *Keyring) Add(id string, key []byte) error {
+	if len(id) == 0 || len(id) > 255 {
+		return fmt.Errorf("key ID must be 1 to 255 bytes long, got %d", len(id))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("failed to create cipher for key '%s': %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("failed to create cipher for key '%s': %w", id, err)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = aead
+	return nil
+}
+
+// Remove removes a key from the keyring. The primary key can not be removed.
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if id == k.primary {
+		return fmt.Errorf("failed to remove primary key '%s'", id)
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// SetPrimary makes the key with the given ID the primary key.
+func (k *Keyring) SetPrimary(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return fmt.Errorf("failed to set primary key '%s': %w", id, ErrUnknownKey)
+	}
+	k.primary = id
+	return nil
+}
+
+// Primary returns the ID of the primary key.
+func (k *Keyring) Primary() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.primary
+}
+
+// get returns the cipher of the key with the given ID.
+func (k *Keyring) get(id string) (cipher.AEAD, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	aead, ok := k.keys[id]
+	return aead, ok
+}
+
+// getPrimary returns the ID and the cipher of the primary key.
+func (k *Keyring) getPrimary() (string, cipher.AEAD) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.primary, k.keys[k.primary]
+}
+
+// EncryptingEncoder is an encoder decorator that encrypts the payloads produced by the wrapped encoder with AES-GCM.
+//
+// Encrypted payloads are stored in an envelope with the following layout:
+//
+//	"SBCE" | version (1 byte) | key ID length (1 byte) | key ID | nonce | ciphertext
+//
+// The header is authenticated together with the ciphertext, so the key ID can not be tampered with.
+// Compression does not work on encrypted data, wrap a CompressingEncoder, not the other way around.
+type EncryptingEncoder struct {
+	inner             Encoder
+	keyring           *Keyring
+	plaintextFallback bool
+}
+
+// NewEncryptingEncoder creates a new EncryptingEncoder that wraps the given encoder.
+func NewEncryptingEncoder(inner Encoder, keyring *Keyring, opts ...EncryptingEncoderOpt) *EncryptingEncoder {
+	e := &EncryptingEncoder{inner: inner, keyring: keyring}
+	// Apply options
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Encode encodes the given value v with the wrapped encoder and encrypts the result with the primary key.
+func (e *EncryptingEncoder) Encode(v any) ([]byte, error) {
+	b, err := e.inner.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	id, aead := e.keyring.getPrimary()
+	header := make([]byte, 0, len(encryptedMagic)+2+len(id))
+	header = append(header, encryptedMagic...)
+	header = append(header, encryptedVersion, byte(len(id)))
+	header = append(header, id...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := append(header, nonce...)
+	return aead.Seal(out, nonce, b, header), nil
+}
+
+// Decode decrypts the envelope with the key referenced by it and decodes the result with the wrapped encoder.
+func (e *EncryptingEncoder) Decode(data []byte, v any) error {
+	return e.DecodeWithHeaders(data, nil, v)
+}
+
+// DecodeWithHeaders decrypts the envelope and decodes the result with the wrapped encoder,
+// passing the headers to it if it is a HeaderDecoder.
+func (e *EncryptingEncoder) DecodeWithHeaders(data []byte, headers map[string]string, v any) error {
+	if !bytes.HasPrefix(data, encryptedMagic) {
+		if e.plaintextFallback {
+			return decodeWithHeaders(e.inner, data, headers, v)
+		}
+		return fmt.Errorf("failed to decrypt value: %w", ErrNotEncrypted)
+	}
+	b, err := e.decrypt(data)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return decodeWithHeaders(e.inner, b, headers, v)
+}
+
+// decrypt opens the envelope.
+func (e *EncryptingEncoder) decrypt(data []byte) ([]byte, error) {
+	rest := data[len(encryptedMagic):]
+	if len(rest) < 2 || rest[0] != encryptedVersion {
+		return nil, ErrInvalidEnvelope
+	}
+	idLen := int(rest[1])
+	rest = rest[2:]
+	if len(rest) < idLen {
+		return nil, ErrInvalidEnvelope
+	}
+	id := string(rest[:idLen])
+	rest = rest[idLen:]
+	aead, ok := e.keyring.get(id)
+	if !ok {
+		return nil, fmt.Errorf("key '%s': %w", id, ErrUnknownKey)
+	}
+	if len(rest) < aead.NonceSize() {
+		return nil, ErrInvalidEnvelope
+	}
+	header := data[:len(data)-len(rest)]
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, header)
+}
+
+// EncryptingEncoderOpt is a function type that modifies the properties of an EncryptingEncoder.
+type EncryptingEncoderOpt func(*EncryptingEncoder)
+
+// WithPlaintextFallback makes the EncryptingEncoder decode payloads that are not encrypted as is.
+// It is intended for the migration of existing plaintext values only.
+func WithPlaintextFallback() EncryptingEncoderOpt {
+	return func(e *EncryptingEncoder) {
+		e.plaintextFallback = true
+	}
+}
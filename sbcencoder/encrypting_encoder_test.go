@@ -0,0 +1,92 @@
+package sbcencoder_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbcencoder"
+)
+
+var (
+	testKey1 = bytes.Repeat([]byte{1}, 32)
+	testKey2 = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestNewKeyringInvalidKey(t *testing.T) {
+	if _, err := sbcencoder.NewKeyring("k1", []byte("short")); err == nil {
+		t.Fail()
+	}
+	if _, err := sbcencoder.NewKeyring("", testKey1); err == nil {
+		t.Fail()
+	}
+}
+
+func TestEncryptingEncoderRoundTrip(t *testing.T) {
+	keyring, _ := sbcencoder.NewKeyring("k1", testKey1)
+	encoder := sbcencoder.NewEncryptingEncoder(sbcencoder.NewJsonEncoder(), keyring)
+	data, err := encoder.Encode(map[string]string{"password": "secret"})
+	if err != nil || bytes.Contains(data, []byte("secret")) {
+		t.Fatalf("unexpected result %q, %v", data, err)
+	}
+	var v map[string]string
+	if err := encoder.Decode(data, &v); err != nil || v["password"] != "secret" {
+		t.Errorf("unexpected result %v, %v", v, err)
+	}
+}
+
+func TestEncryptingEncoderKeyRotation(t *testing.T) {
+	keyring, _ := sbcencoder.NewKeyring("k1", testKey1)
+	encoder := sbcencoder.NewEncryptingEncoder(sbcencoder.NewJsonEncoder(), keyring)
+	old, _ := encoder.Encode("old")
+	if err := keyring.Add("k2", testKey2); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.SetPrimary("k2"); err != nil || keyring.Primary() != "k2" {
+		t.Fatal(err)
+	}
+	current, _ := encoder.Encode("new")
+	var v string
+	if err := encoder.Decode(old, &v); err != nil || v != "old" {
+		t.Errorf("failed to decode value encrypted with the old key: %v", err)
+	}
+	if err := keyring.Remove("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := encoder.Decode(old, &v); !errors.Is(err, sbcencoder.ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+	if err := encoder.Decode(current, &v); err != nil || v != "new" {
+		t.Errorf("failed to decode value encrypted with the new key: %v", err)
+	}
+	if err := keyring.Remove("k2"); err == nil {
+		t.Error("Expected error on removing primary key")
+	}
+}
+
+func TestEncryptingEncoderDecodeTampered(t *testing.T) {
+	keyring, _ := sbcencoder.NewKeyring("k1", testKey1)
+	encoder := sbcencoder.NewEncryptingEncoder(sbcencoder.NewJsonEncoder(), keyring)
+	data, _ := encoder.Encode("value")
+	data[len(data)-1] ^= 0xff
+	var v string
+	if err := encoder.Decode(data, &v); err == nil {
+		t.Fail()
+	}
+	if err := encoder.Decode(data[:8], &v); !errors.Is(err, sbcencoder.ErrInvalidEnvelope) {
+		t.Errorf("Expected ErrInvalidEnvelope, got %v", err)
+	}
+}
+
+func TestEncryptingEncoderDecodePlaintext(t *testing.T) {
+	keyring, _ := sbcencoder.NewKeyring("k1", testKey1)
+	encoder := sbcencoder.NewEncryptingEncoder(sbcencoder.NewJsonEncoder(), keyring)
+	var v string
+	if err := encoder.Decode([]byte(`"value"`), &v); !errors.Is(err, sbcencoder.ErrNotEncrypted) {
+		t.Errorf("Expected ErrNotEncrypted, got %v", err)
+	}
+	encoder = sbcencoder.NewEncryptingEncoder(sbcencoder.NewJsonEncoder(), keyring, sbcencoder.WithPlaintextFallback())
+	if err := encoder.Decode([]byte(`"value"`), &v); err != nil || v != "value" {
+		t.Errorf("unexpected result %q, %v", v, err)
+	}
+}
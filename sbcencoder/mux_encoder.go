@@ -0,0 +1,252 @@
+package sbcencoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Encoder encodes values into byte slices and decodes them back.
+// It has the same method set as sbc.Encoder, so any sbc.Encoder can be passed where this interface is expected.
+type Encoder interface {
+
+	// Encode encodes the input value v into a byte slice and returns it along with an error if the encoding fails.
+	Encode(v any) ([]byte, error)
+
+	// Decode decodes the provided byte slice into the target variable v of any type.
+	// Returns an error if the decoding process fails.
+	Decode(data []byte, v any) error
+}
+
+// HeaderDecoder is an Encoder that uses the transport headers of a payload as hints for decoding.
+// It has the same method set as sbc.HeaderDecoder. The decorators of this package implement it and
+// forward the headers to the wrapped encoder, so a wrapped MuxEncoder keeps its header hints.
+type HeaderDecoder interface {
+
+	// DecodeWithHeaders decodes the provided byte slice into the target variable v using the headers as hints.
+	DecodeWithHeaders(data []byte, headers map[string]string, v any) error
+}
+
+// decodeWithHeaders decodes the byte slice with the encoder, passing the headers if it is a HeaderDecoder.
+func decodeWithHeaders(e Encoder, data []byte, headers map[string]string, v any) error {
+	if hd, ok := e.(HeaderDecoder); ok {
+		return hd.DecodeWithHeaders(data, headers, v)
+	}
+	return e.Decode(data, v)
+}
+
+const (
+	// FormatJSON is the name of the JSON format.
+	FormatJSON = "json"
+
+	// FormatYAML is the name of the YAML format.
+	FormatYAML = "yaml"
+)
+
+const (
+	// ContentTypeHeader is the transport header that carries the content type (or format name) of a payload.
+	ContentTypeHeader = "Content-Type"
+
+	// FlagsHeader is the transport header that carries the Consul KVPair.Flags value of a payload.
+	FlagsHeader = "Flags"
+)
+
+// envelopePrefix starts the optional first line of a payload that names its format, e.g. "#sbc:format=yaml\n".
+// The line is a comment in YAML, so enveloped YAML documents stay valid YAML.
+const envelopePrefix = "#sbc:format="
+
+// ErrUnknownFormat is an error that is returned when the format of a payload is not registered.
+var ErrUnknownFormat = errors.New("unknown payload format")
+
+// Format describes a payload format known to the MuxEncoder.
+type Format struct {
+
+	// Name is the short name of the format, e.g. "json". It is used in the envelope header.
+	Name string
+
+	// ContentType is the MIME type of the format, matched against the ContentTypeHeader of an update.
+	ContentType string
+
+	// Flags is the Consul KVPair.Flags value that marks the format, zero means the format has no flags marker.
+	Flags uint64
+
+	// Encoder encodes and decodes the payloads of the format.
+	Encoder Encoder
+
+	// Detect reports whether the payload looks like this format, nil means the format is never sniffed.
+	Detect func(data []byte) bool
+}
+
+// JsonFormat returns the JSON format backed by the JsonEncoder.
+func JsonFormat() Format {
+	return Format{
+		Name:        FormatJSON,
+		ContentType: "application/json",
+		Encoder:     NewJsonEncoder(),
+		Detect:      json.Valid,
+	}
+}
+
+// YamlFormat returns the YAML format backed by the YamlEncoder.
+// Any payload that is not valid JSON is detected as YAML.
+func YamlFormat() Format {
+	return Format{
+		Name:        FormatYAML,
+		ContentType: "application/yaml",
+		Encoder:     NewYamlEncoder(),
+		Detect: func(data []byte) bool {
+			return !json.Valid(data)
+		},
+	}
+}
+
+// MuxEncoder is an encoder that detects the format of every payload and delegates to the encoder registered for it.
+// Encode always writes the preferred format.
+//
+// The format of a payload is resolved in the following order:
+//   - transport headers (ContentTypeHeader or FlagsHeader), see DecodeWithHeaders;
+//   - the envelope header written by the encoder with the WithEnvelope option;
+//   - the Detect functions of the registered formats, in registration order;
+//   - the preferred format.
+type MuxEncoder struct {
+	preferred string
+	formats   []Format
+	envelope  bool
+}
+
+// NewMuxEncoder creates a new MuxEncoder that writes the preferred format.
+// JSON and YAML formats are registered by default, WithFormat registers more formats or replaces the defaults.
+func NewMuxEncoder(preferred string, opts ...MuxEncoderOpt) (*MuxEncoder, error) {
+	m := &MuxEncoder{preferred: preferred, formats: []Format{JsonFormat(), YamlFormat()}}
+	// Apply options
+	for _, opt := range opts {
+		opt(m)
+	}
+	if _, ok := m.format(preferred); !ok {
+		return nil, fmt.Errorf("preferred format '%s': %w", preferred, ErrUnknownFormat)
+	}
+	return m, nil
+}
+
+// Encode encodes the given value v into a byte slice using the preferred format.
+func (m *MuxEncoder) Encode(v any) ([]byte, error) {
+	f, _ := m.format(m.preferred)
+	b, err := f.Encoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	if !m.envelope {
+		return b, nil
+	}
+	return append([]byte(envelopePrefix+f.Name+"\n"), b...), nil
+}
+
+// Decode detects the format of the byte slice and decodes it into v.
+func (m *MuxEncoder) Decode(data []byte, v any) error {
+	return m.DecodeWithHeaders(data, nil, v)
+}
+
+// DecodeWithHeaders decodes the byte slice into v, using the transport headers as the first format hint.
+// The ContentTypeHeader is matched against the content types and names of the registered formats,
+// the FlagsHeader is matched against their Consul flags.
+func (m *MuxEncoder) DecodeWithHeaders(data []byte, headers map[string]string, v any) error {
+	if f, ok := m.formatFromHeaders(headers); ok {
+		return f.Encoder.Decode(stripEnvelope(data), v)
+	}
+	if name, rest, ok := parseEnvelope(data); ok {
+		f, ok := m.format(name)
+		if !ok {
+			return fmt.Errorf("failed to decode value in format '%s': %w", name, ErrUnknownFormat)
+		}
+		return f.Encoder.Decode(rest, v)
+	}
+	for _, f := range m.formats {
+		if f.Detect != nil && f.Detect(data) {
+			return f.Encoder.Decode(data, v)
+		}
+	}
+	f, _ := m.format(m.preferred)
+	return f.Encoder.Decode(data, v)
+}
+
+// format returns the registered format with the given name.
+func (m *MuxEncoder) format(name string) (Format, bool) {
+	for _, f := range m.formats {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// formatFromHeaders returns the registered format referenced by the transport headers.
+func (m *MuxEncoder) formatFromHeaders(headers map[string]string) (Format, bool) {
+	if ct, ok := headers[ContentTypeHeader]; ok {
+		// drop parameters like "; charset=utf-8"
+		ct, _, _ = strings.Cut(ct, ";")
+		ct = strings.TrimSpace(ct)
+		for _, f := range m.formats {
+			if strings.EqualFold(f.ContentType, ct) || strings.EqualFold(f.Name, ct) {
+				return f, true
+			}
+		}
+	}
+	if raw, ok := headers[FlagsHeader]; ok {
+		flags, err := strconv.ParseUint(raw, 10, 64)
+		if err == nil && flags != 0 {
+			for _, f := range m.formats {
+				if f.Flags == flags {
+					return f, true
+				}
+			}
+		}
+	}
+	return Format{}, false
+}
+
+// parseEnvelope splits the envelope header from the payload and returns the format name it references.
+func parseEnvelope(data []byte) (string, []byte, bool) {
+	if !bytes.HasPrefix(data, []byte(envelopePrefix)) {
+		return "", nil, false
+	}
+	line, rest, ok := bytes.Cut(data[len(envelopePrefix):], []byte("\n"))
+	if !ok {
+		return "", nil, false
+	}
+	return string(bytes.TrimSpace(line)), rest, true
+}
+
+// stripEnvelope removes the envelope header from the payload if present.
+func stripEnvelope(data []byte) []byte {
+	if _, rest, ok := parseEnvelope(data); ok {
+		return rest
+	}
+	return data
+}
+
+// MuxEncoderOpt is a function type that modifies the properties of a MuxEncoder.
+type MuxEncoderOpt func(*MuxEncoder)
+
+// WithFormat registers a format in the MuxEncoder.
+// A format with the same name as an already registered one replaces it in place.
+func WithFormat(f Format) MuxEncoderOpt {
+	return func(m *MuxEncoder) {
+		for i := range m.formats {
+			if m.formats[i].Name == f.Name {
+				m.formats[i] = f
+				return
+			}
+		}
+		m.formats = append(m.formats, f)
+	}
+}
+
+// WithEnvelope makes the MuxEncoder prefix encoded payloads with an envelope header naming their format.
+func WithEnvelope() MuxEncoderOpt {
+	return func(m *MuxEncoder) {
+		m.envelope = true
+	}
+}
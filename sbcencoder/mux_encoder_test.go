@@ -0,0 +1,108 @@
+package sbcencoder_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbcencoder"
+)
+
+func TestMuxEncoderUnknownPreferredFormat(t *testing.T) {
+	_, err := sbcencoder.NewMuxEncoder("toml")
+	if !errors.Is(err, sbcencoder.ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestMuxEncoderEncodePreferredFormat(t *testing.T) {
+	encoder, _ := sbcencoder.NewMuxEncoder(sbcencoder.FormatYAML)
+	data, err := encoder.Encode(map[string]string{"key": "value"})
+	if err != nil || string(data) != "key: value\n" {
+		t.Errorf("unexpected result %q, %v", data, err)
+	}
+}
+
+func TestMuxEncoderEncodeWithEnvelope(t *testing.T) {
+	encoder, _ := sbcencoder.NewMuxEncoder(sbcencoder.FormatJSON, sbcencoder.WithEnvelope())
+	data, err := encoder.Encode(map[string]string{"key": "value"})
+	if err != nil || string(data) != "#sbc:format=json\n{\"key\":\"value\"}" {
+		t.Errorf("unexpected result %q, %v", data, err)
+	}
+	var v map[string]string
+	if err := encoder.Decode(data, &v); err != nil || v["key"] != "value" {
+		t.Errorf("unexpected result %v, %v", v, err)
+	}
+}
+
+func TestMuxEncoderDecodeDetectsFormat(t *testing.T) {
+	encoder, _ := sbcencoder.NewMuxEncoder(sbcencoder.FormatYAML)
+	for _, payload := range []string{`{"key":"value"}`, "key: value\n", "#sbc:format=yaml\nkey: value\n"} {
+		var v map[string]string
+		if err := encoder.Decode([]byte(payload), &v); err != nil || v["key"] != "value" {
+			t.Errorf("payload %q: unexpected result %v, %v", payload, v, err)
+		}
+	}
+}
+
+func TestMuxEncoderDecodeUnknownEnvelope(t *testing.T) {
+	encoder, _ := sbcencoder.NewMuxEncoder(sbcencoder.FormatJSON)
+	var v map[string]string
+	err := encoder.Decode([]byte("#sbc:format=toml\nkey = 'value'"), &v)
+	if !errors.Is(err, sbcencoder.ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestMuxEncoderDecodeWithHeaders(t *testing.T) {
+	yaml := sbcencoder.YamlFormat()
+	yaml.Flags = 2
+	encoder, _ := sbcencoder.NewMuxEncoder(sbcencoder.FormatJSON, sbcencoder.WithFormat(yaml))
+	var v map[string]string
+	err := encoder.DecodeWithHeaders([]byte("key: value\n"), map[string]string{sbcencoder.FlagsHeader: "2"}, &v)
+	if err != nil || v["key"] != "value" {
+		t.Errorf("unexpected result %v, %v", v, err)
+	}
+	// the header wins over the sniffed format
+	err = encoder.DecodeWithHeaders([]byte("key: value\n"), map[string]string{sbcencoder.ContentTypeHeader: "application/json; charset=utf-8"}, &v)
+	if err == nil {
+		t.Error("Expected JSON decoding error")
+	}
+}
+
+func TestMuxEncoderWrappedDecodeWithHeaders(t *testing.T) {
+	keyring, _ := sbcencoder.NewKeyring("k1", testKey1)
+	key := newTestSigningKey(1)
+	mux, _ := sbcencoder.NewMuxEncoder(sbcencoder.FormatJSON)
+	tests := []struct {
+		name string
+		wrap func(inner sbcencoder.Encoder) sbcencoder.HeaderDecoder
+	}{
+		{"compressing", func(inner sbcencoder.Encoder) sbcencoder.HeaderDecoder {
+			return sbcencoder.NewCompressingEncoder(inner, sbcencoder.WithCompressionThreshold(0))
+		}},
+		{"encrypting", func(inner sbcencoder.Encoder) sbcencoder.HeaderDecoder {
+			return sbcencoder.NewEncryptingEncoder(inner, keyring)
+		}},
+		{"signing", func(inner sbcencoder.Encoder) sbcencoder.HeaderDecoder {
+			encoder, _ := sbcencoder.NewSigningEncoder(inner, sbcencoder.WithSigningKey("deploy", key))
+			return encoder
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.wrap(sbcencoder.NewYamlEncoder()).(sbcencoder.Encoder).Encode(map[string]string{"key": "value"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoder := tt.wrap(mux)
+			var v map[string]string
+			if err := decoder.DecodeWithHeaders(data, map[string]string{sbcencoder.ContentTypeHeader: "yaml"}, &v); err != nil || v["key"] != "value" {
+				t.Errorf("unexpected result %v, %v", v, err)
+			}
+			// the header reaches the wrapped MuxEncoder and wins over the sniffed format
+			if err := decoder.DecodeWithHeaders(data, map[string]string{sbcencoder.ContentTypeHeader: "application/json"}, &v); err == nil {
+				t.Error("Expected JSON decoding error")
+			}
+		})
+	}
+}
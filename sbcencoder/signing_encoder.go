@@ -0,0 +1,165 @@
+package sbcencoder
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+// signedMagic starts every payload produced by the SigningEncoder.
+var signedMagic = []byte("SBCS")
+
+// signedVersion is the version of the signed envelope format.
+const signedVersion = 1
+
+var (
+	// ErrNotSigned is an error that is returned when a payload is not a signed envelope.
+	ErrNotSigned = errors.New("payload is not signed")
+
+	// ErrUntrustedKey is an error that is returned when a payload is signed by a key that is not trusted.
+	ErrUntrustedKey = errors.New("untrusted signing key")
+
+	// ErrInvalidSignature is an error that is returned when the signature does not match the payload.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrNoSigningKey is an error that is returned on Encode when the SigningEncoder has no private key.
+	ErrNoSigningKey = errors.New("no signing key")
+
+	// ErrInvalidSigningKey is an error that is returned when a signing or trusted key has a wrong size or ID.
+	ErrInvalidSigningKey = errors.New("invalid signing key")
+)
+
+// SigningEncoder is an encoder decorator that signs the payloads produced by the wrapped encoder with Ed25519
+// and verifies the signature before decoding.
+//
+// Signed payloads are stored in an envelope with the following layout:
+//
+//	"SBCS" | version (1 byte) | key ID length (1 byte) | key ID | signature (64 bytes) | payload
+//
+// The signature covers the header and the payload. Decode rejects unsigned payloads, payloads signed by
+// keys that are not trusted and tampered payloads, so such values never reach the subscription.
+// Subscribers only need the trusted public keys; the private key is required by the publishing side only.
+type SigningEncoder struct {
+	inner      Encoder
+	signingID  string
+	signingKey ed25519.PrivateKey
+	trusted    map[string]ed25519.PublicKey
+}
+
+// NewSigningEncoder creates a new SigningEncoder that wraps the given encoder.
+// It fails with ErrInvalidSigningKey when a key has a wrong size or its ID is not 1 to 255 bytes long.
+func NewSigningEncoder(inner Encoder, opts ...SigningEncoderOpt) (*SigningEncoder, error) {
+	s := &SigningEncoder{inner: inner, trusted: make(map[string]ed25519.PublicKey)}
+	// Apply options
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Encode encodes the given value v with the wrapped encoder and signs the result.
+func (s *SigningEncoder) Encode(v any) ([]byte, error) {
+	if s.signingKey == nil {
+		return nil, fmt.Errorf("failed to sign value: %w", ErrNoSigningKey)
+	}
+	b, err := s.inner.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, len(signedMagic)+2+len(s.signingID))
+	header = append(header, signedMagic...)
+	header = append(header, signedVersion, byte(len(s.signingID)))
+	header = append(header, s.signingID...)
+	signature := ed25519.Sign(s.signingKey, append(bytes.Clone(header), b...))
+	out := make([]byte, 0, len(header)+len(signature)+len(b))
+	out = append(out, header...)
+	out = append(out, signature...)
+	return append(out, b...), nil
+}
+
+// Decode verifies the signature of the envelope and decodes the payload with the wrapped encoder.
+func (s *SigningEncoder) Decode(data []byte, v any) error {
+	return s.DecodeWithHeaders(data, nil, v)
+}
+
+// DecodeWithHeaders verifies the signature of the envelope and decodes the payload with the wrapped encoder,
+// passing the headers to it if it is a HeaderDecoder.
+func (s *SigningEncoder) DecodeWithHeaders(data []byte, headers map[string]string, v any) error {
+	b, err := s.verify(data)
+	if err != nil {
+		return fmt.Errorf("failed to verify value: %w", err)
+	}
+	return decodeWithHeaders(s.inner, b, headers, v)
+}
+
+// verify checks the envelope and returns the signed payload.
+func (s *SigningEncoder) verify(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, signedMagic) {
+		return nil, ErrNotSigned
+	}
+	rest := data[len(signedMagic):]
+	if len(rest) < 2 || rest[0] != signedVersion {
+		return nil, ErrInvalidEnvelope
+	}
+	idLen := int(rest[1])
+	rest = rest[2:]
+	if len(rest) < idLen+ed25519.SignatureSize {
+		return nil, ErrInvalidEnvelope
+	}
+	id := string(rest[:idLen])
+	header := data[:len(signedMagic)+2+idLen]
+	signature := rest[idLen : idLen+ed25519.SignatureSize]
+	payload := rest[idLen+ed25519.SignatureSize:]
+	key, ok := s.trusted[id]
+	if !ok {
+		return nil, fmt.Errorf("key '%s': %w", id, ErrUntrustedKey)
+	}
+	if !ed25519.Verify(key, append(bytes.Clone(header), payload...), signature) {
+		return nil, ErrInvalidSignature
+	}
+	return payload, nil
+}
+
+// SigningEncoderOpt is a function type that modifies the properties of a SigningEncoder.
+// It returns an error when the option is invalid.
+type SigningEncoderOpt func(*SigningEncoder) error
+
+// WithSigningKey sets the private key used to sign payloads on Encode.
+// The matching public key is trusted as well.
+func WithSigningKey(id string, key ed25519.PrivateKey) SigningEncoderOpt {
+	return func(s *SigningEncoder) error {
+		if err := checkSigningKey(id, len(key), ed25519.PrivateKeySize); err != nil {
+			return err
+		}
+		s.signingID = id
+		s.signingKey = key
+		s.trusted[id] = key.Public().(ed25519.PublicKey)
+		return nil
+	}
+}
+
+// WithTrustedKey adds a public key whose signatures are accepted on Decode.
+// Several keys can be trusted at once to rotate the signing key.
+func WithTrustedKey(id string, key ed25519.PublicKey) SigningEncoderOpt {
+	return func(s *SigningEncoder) error {
+		if err := checkSigningKey(id, len(key), ed25519.PublicKeySize); err != nil {
+			return err
+		}
+		s.trusted[id] = key
+		return nil
+	}
+}
+
+// checkSigningKey checks the ID and the size of a key, ed25519 panics on keys of a wrong size.
+func checkSigningKey(id string, size, expected int) error {
+	if len(id) == 0 || len(id) > 255 {
+		return fmt.Errorf("%w: key ID must be 1 to 255 bytes long, got %d", ErrInvalidSigningKey, len(id))
+	}
+	if size != expected {
+		return fmt.Errorf("%w: key '%s' must be %d bytes long, got %d", ErrInvalidSigningKey, id, expected, size)
+	}
+	return nil
+}
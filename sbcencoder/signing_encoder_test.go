@@ -0,0 +1,88 @@
+package sbcencoder_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbcencoder"
+)
+
+func newTestSigningKey(seed byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+func newTestSigningEncoder(t *testing.T, opts ...sbcencoder.SigningEncoderOpt) *sbcencoder.SigningEncoder {
+	t.Helper()
+	encoder, err := sbcencoder.NewSigningEncoder(sbcencoder.NewJsonEncoder(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoder
+}
+
+func TestSigningEncoderRoundTrip(t *testing.T) {
+	key := newTestSigningKey(1)
+	signer := newTestSigningEncoder(t, sbcencoder.WithSigningKey("deploy", key))
+	data, err := signer.Encode(map[string]string{"key": "value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := newTestSigningEncoder(t, sbcencoder.WithTrustedKey("deploy", key.Public().(ed25519.PublicKey)))
+	var v map[string]string
+	if err := verifier.Decode(data, &v); err != nil || v["key"] != "value" {
+		t.Errorf("unexpected result %v, %v", v, err)
+	}
+}
+
+func TestSigningEncoderEncodeWithoutKey(t *testing.T) {
+	verifier := newTestSigningEncoder(t)
+	if _, err := verifier.Encode("value"); !errors.Is(err, sbcencoder.ErrNoSigningKey) {
+		t.Errorf("Expected ErrNoSigningKey, got %v", err)
+	}
+}
+
+func TestSigningEncoderDecodeRejected(t *testing.T) {
+	key := newTestSigningKey(1)
+	signer := newTestSigningEncoder(t, sbcencoder.WithSigningKey("deploy", key))
+	data, _ := signer.Encode("value")
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-2] = 'X'
+	untrusted := newTestSigningEncoder(t, sbcencoder.WithTrustedKey("deploy", newTestSigningKey(2).Public().(ed25519.PublicKey)))
+	cases := []struct {
+		name    string
+		encoder *sbcencoder.SigningEncoder
+		data    []byte
+		err     error
+	}{
+		{"unsigned", signer, []byte(`"value"`), sbcencoder.ErrNotSigned},
+		{"tampered", signer, tampered, sbcencoder.ErrInvalidSignature},
+		{"truncated", signer, data[:10], sbcencoder.ErrInvalidEnvelope},
+		{"wrong key", untrusted, data, sbcencoder.ErrInvalidSignature},
+		{"unknown key", newTestSigningEncoder(t), data, sbcencoder.ErrUntrustedKey},
+	}
+	for _, c := range cases {
+		var v string
+		if err := c.encoder.Decode(c.data, &v); !errors.Is(err, c.err) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestSigningEncoderInvalidKey(t *testing.T) {
+	key := newTestSigningKey(1)
+	cases := []struct {
+		name string
+		opt  sbcencoder.SigningEncoderOpt
+	}{
+		{"short private key", sbcencoder.WithSigningKey("deploy", key[:10])},
+		{"short public key", sbcencoder.WithTrustedKey("deploy", ed25519.PublicKey{1, 2, 3})},
+		{"empty key ID", sbcencoder.WithTrustedKey("", key.Public().(ed25519.PublicKey))},
+	}
+	for _, c := range cases {
+		if _, err := sbcencoder.NewSigningEncoder(sbcencoder.NewJsonEncoder(), c.opt); !errors.Is(err, sbcencoder.ErrInvalidSigningKey) {
+			t.Errorf("%s: expected ErrInvalidSigningKey, got %v", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package sbcencoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YamlEncoder is a type that provides encoding and decoding functionality for YAML data.
+//
+// The value is routed through encoding/json, so the `json` struct tags of the config type are honoured and
+// the same type can be stored either as JSON or as YAML.
+type YamlEncoder struct{}
+
+// NewYamlEncoder creates a new YamlEncoder.
+func NewYamlEncoder() *YamlEncoder {
+	return &YamlEncoder{}
+}
+
+// Encode encodes the given value v into a byte slice.
+func (y YamlEncoder) Encode(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	out, err := yaml.Marshal(normalizeNumbers(generic))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	return out, nil
+}
+
+// Decode decodes the byte slice into type T.
+func (y YamlEncoder) Decode(data []byte, v any) error {
+	var generic any
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return fmt.Errorf("failed to decode value: %w", err)
+	}
+	b, err := json.Marshal(generic)
+	if err != nil {
+		return fmt.Errorf("failed to decode value: %w", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("failed to decode value: %w", err)
+	}
+	return nil
+}
+
+// normalizeNumbers replaces json.Number values with int64 or float64, so they are written as YAML numbers
+// instead of quoted strings.
+func normalizeNumbers(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			val[k] = normalizeNumbers(item)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = normalizeNumbers(item)
+		}
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
@@ -0,0 +1,47 @@
+package sbcencoder_test
+
+import (
+	"testing"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbcencoder"
+)
+
+type yamlTestConfig struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Hosts []string `json:"hosts"`
+}
+
+func TestYamlEncoderEncodeValidData(t *testing.T) {
+	encoder := sbcencoder.NewYamlEncoder()
+	data, err := encoder.Encode(yamlTestConfig{Name: "app", Count: 2})
+	if err != nil || string(data) != "count: 2\nhosts: null\nname: app\n" {
+		t.Errorf("unexpected result %q, %v", data, err)
+	}
+}
+
+func TestYamlEncoderEncodeInvalidData(t *testing.T) {
+	encoder := sbcencoder.NewYamlEncoder()
+	_, err := encoder.Encode(make(chan int))
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestYamlEncoderDecodeValidData(t *testing.T) {
+	encoder := sbcencoder.NewYamlEncoder()
+	var v yamlTestConfig
+	err := encoder.Decode([]byte("name: app\ncount: 3\nhosts:\n  - a\n  - b\n"), &v)
+	if err != nil || v.Name != "app" || v.Count != 3 || len(v.Hosts) != 2 {
+		t.Errorf("unexpected result %+v, %v", v, err)
+	}
+}
+
+func TestYamlEncoderDecodeInvalidData(t *testing.T) {
+	encoder := sbcencoder.NewYamlEncoder()
+	var v yamlTestConfig
+	err := encoder.Decode([]byte("name: [app"), &v)
+	if err == nil {
+		t.Fail()
+	}
+}
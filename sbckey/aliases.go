@@ -0,0 +1,56 @@
+package sbckey
+
+import (
+	"context"
+	"fmt"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+)
+
+// aliasKeyBuilder is a MultiKeyBuilder that resolves to the keys of several key builders.
+type aliasKeyBuilder[T any] struct {
+	builders []sbc.KeyBuilder[T]
+}
+
+// Aliases returns a KeyBuilder that resolves to the key of the primary key builder followed by the keys of
+// the legacy key builders, ordered by priority. The subscription watches all the keys and uses the value of
+// the highest-priority key that exists, so services can read the new key and fall back to the old one
+// while a key migration is in progress. Use sbc.Subscription.Key to see which key is active.
+//
+// Example usage:
+//
+//	builder := Aliases[Config](
+//		ConsulTypeKeyBuilder[Config]("prod/"),
+//		ConsulDefaultKeyBuilder[Config]("prod/"),
+//	)
+func Aliases[T any](primary sbc.KeyBuilder[T], legacy ...sbc.KeyBuilder[T]) sbc.KeyBuilder[T] {
+	return aliasKeyBuilder[T]{builders: append([]sbc.KeyBuilder[T]{primary}, legacy...)}
+}
+
+// BuildKey returns the key of the primary key builder.
+func (a aliasKeyBuilder[T]) BuildKey(t T) string {
+	return a.builders[0].BuildKey(t)
+}
+
+// BuildKeyContext returns the key of the primary key builder built with the context.
+func (a aliasKeyBuilder[T]) BuildKeyContext(ctx context.Context, t T) (string, error) {
+	return sbc.BuildKeyContext(ctx, a.builders[0], t)
+}
+
+// BuildKeys returns the keys of all key builders ordered by priority, duplicates are dropped.
+func (a aliasKeyBuilder[T]) BuildKeys(ctx context.Context, t T) ([]string, error) {
+	keys := make([]string, 0, len(a.builders))
+	seen := make(map[string]bool, len(a.builders))
+	for i, kb := range a.builders {
+		key, err := sbc.BuildKeyContext(ctx, kb, t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build alias key %d: %w", i, err)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
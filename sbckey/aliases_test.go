@@ -0,0 +1,74 @@
+package sbckey_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+	"github.com/Autodoc-Technology/streaming-based-config/sbckey"
+)
+
+func TestAliasesBuildKey(t *testing.T) {
+	builder := sbckey.Aliases[int](sbckey.ConsulDefaultKeyBuilder[int]("v2/"), sbckey.DefaultKeyBuilder[int]())
+	if result := builder.BuildKey(0); result != "v2/int" {
+		t.Errorf("Expected 'v2/int', got '%s'", result)
+	}
+}
+
+func TestAliasesBuildKeys(t *testing.T) {
+	builder := sbckey.Aliases[int](
+		sbckey.ConsulDefaultKeyBuilder[int]("v2/"),
+		sbckey.DefaultKeyBuilder[int](),
+		sbckey.ConsulDefaultKeyBuilder[int]("v2/"),
+	)
+	keys, err := sbc.BuildKeys(context.Background(), builder, 0)
+	if err != nil || len(keys) != 2 || keys[0] != "v2/int" || keys[1] != "int" {
+		t.Errorf("Expected [v2/int int], got %v, %v", keys, err)
+	}
+}
+
+func TestAliasesBuildKeysError(t *testing.T) {
+	template, _ := sbckey.NewTemplateKeyBuilder[int]("{env}/{type}")
+	builder := sbckey.Aliases[int](sbckey.DefaultKeyBuilder[int](), template)
+	if _, err := sbc.BuildKeys(context.Background(), builder, 0); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestAliasesWrapped(t *testing.T) {
+	template, _ := sbckey.NewTemplateKeyBuilder[int]("limits/tenant-{tenant}")
+	legacy, _ := sbckey.NewTemplateKeyBuilder[int]("old/limits-{tenant}")
+	detector := sbckey.NewCollisionDetector(func(err *sbckey.CollisionError) { t.Errorf("unexpected collision %v", err) })
+	ctx := sbc.ContextWithKeyParams(context.Background(), sbc.KeyParams{"tenant": "42"})
+	tests := []struct {
+		name    string
+		builder sbc.KeyBuilder[int]
+		keys    []string
+	}{
+		{"collisions of aliases", sbckey.DetectCollisions[int](detector, sbckey.Aliases[int](template, legacy)),
+			[]string{"limits/tenant-42", "old/limits-42"}},
+		{"explicit of aliases", sbckey.ExplicitKeyBuilder[int](sbckey.Aliases[int](template, legacy)),
+			[]string{"limits/tenant-42", "old/limits-42"}},
+		{"aliases of collisions", sbckey.Aliases[int](sbckey.DetectCollisions[int](detector, template), legacy),
+			[]string{"limits/tenant-42", "old/limits-42"}},
+		{"aliases of explicit", sbckey.Aliases[int](sbckey.ExplicitKeyBuilder[int](template), legacy),
+			[]string{"limits/tenant-42", "old/limits-42"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := sbc.BuildKeys(ctx, tt.builder, 0)
+			if err != nil || !slices.Equal(keys, tt.keys) {
+				t.Errorf("Expected %v, got %v, %v", tt.keys, keys, err)
+			}
+			key, err := sbc.BuildKeyContext(ctx, tt.builder, 0)
+			if err != nil || key != tt.keys[0] {
+				t.Errorf("Expected '%s', got '%s', %v", tt.keys[0], key, err)
+			}
+			if _, err := sbc.BuildKeys(context.Background(), tt.builder, 0); !errors.Is(err, sbckey.ErrUnresolvedPlaceholder) {
+				t.Errorf("Expected ErrUnresolvedPlaceholder, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package sbckey
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+)
+
+// CollisionError is an error that is reported when keys of different types resolve to the same key.
+type CollisionError struct {
+	Key      string
+	Existing reflect.Type
+	New      reflect.Type
+}
+
+// Error returns the string representation of the CollisionError.
+func (e *CollisionError) Error() string {
+	return fmt.Sprintf("key '%s' of type %s collides with type %s",
+		e.Key, TypeIdentity(e.New), TypeIdentity(e.Existing))
+}
+
+// CollisionDetector records the keys built for every type and reports keys shared by different types.
+// Several subscriptions of the same type with the same key are not a collision. CollisionDetector is safe
+// for concurrent use.
+type CollisionDetector struct {
+	mu          sync.Mutex
+	keys        map[string]reflect.Type
+	onCollision func(err *CollisionError)
+}
+
+// NewCollisionDetector creates a new CollisionDetector that calls onCollision for every detected collision.
+// With a nil onCollision, the key builders wrapped by DetectCollisions return the *CollisionError instead,
+// so the subscription of the colliding type fails.
+func NewCollisionDetector(onCollision func(err *CollisionError)) *CollisionDetector {
+	return &CollisionDetector{keys: make(map[string]reflect.Type), onCollision: onCollision}
+}
+
+// register records the key of the type and reports a collision with a different type. The collision
+// is returned when the detector has no onCollision callback.
+func (d *CollisionDetector) register(key string, t reflect.Type) error {
+	d.mu.Lock()
+	existing, ok := d.keys[key]
+	if !ok {
+		d.keys[key] = t
+	}
+	d.mu.Unlock()
+	if !ok || existing == t {
+		return nil
+	}
+	err := &CollisionError{Key: key, Existing: existing, New: t}
+	if d.onCollision == nil {
+		return err
+	}
+	d.onCollision(err)
+	return nil
+}
+
+// DetectCollisions wraps the KeyBuilder, so every built key is checked by the detector. The errors of
+// a sbc.ContextKeyBuilder, e.g. the unresolved placeholders of a TemplateKeyBuilder, are returned
+// and no key is checked. All the keys of a sbc.MultiKeyBuilder, e.g. of Aliases, are checked.
+// Share one detector between all subscribers of a process:
+//
+//	detector := sbckey.NewCollisionDetector(nil)
+//	billing := sbc.NewSubscriber[billing.Config](transport, sbckey.DetectCollisions(detector, sbckey.DefaultKeyBuilder[billing.Config]()))
+//	shipping := sbc.NewSubscriber[shipping.Config](transport, sbckey.DetectCollisions(detector, sbckey.DefaultKeyBuilder[shipping.Config]()))
+func DetectCollisions[T any](d *CollisionDetector, kb sbc.KeyBuilder[T]) sbc.KeyBuilder[T] {
+	return collisionKeyBuilder[T]{detector: d, kb: kb, t: reflect.TypeFor[T]()}
+}
+
+// collisionKeyBuilder is a MultiKeyBuilder that checks the keys of the wrapped KeyBuilder with a detector.
+type collisionKeyBuilder[T any] struct {
+	detector *CollisionDetector
+	kb       sbc.KeyBuilder[T]
+	t        reflect.Type
+}
+
+// BuildKey returns the checked key built with a background context, or an empty key on error.
+func (c collisionKeyBuilder[T]) BuildKey(v T) string {
+	key, err := c.BuildKeyContext(context.Background(), v)
+	if err != nil {
+		return ""
+	}
+	return key
+}
+
+// BuildKeyContext returns the checked key of the wrapped KeyBuilder.
+func (c collisionKeyBuilder[T]) BuildKeyContext(ctx context.Context, v T) (string, error) {
+	key, err := sbc.BuildKeyContext(ctx, c.kb, v)
+	if err != nil {
+		return "", err
+	}
+	if err := c.detector.register(key, c.t); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// BuildKeys returns the checked keys of the wrapped KeyBuilder.
+func (c collisionKeyBuilder[T]) BuildKeys(ctx context.Context, v T) ([]string, error) {
+	keys, err := sbc.BuildKeys(ctx, c.kb, v)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if err := c.detector.register(key, c.t); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,56 @@
+package sbckey_test
+
+import (
+	"context"
+	"errors"
+	htmltemplate "html/template"
+	"reflect"
+	"testing"
+	"text/template"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+	"github.com/Autodoc-Technology/streaming-based-config/sbckey"
+)
+
+func TestDetectCollisionsReportsDifferentTypes(t *testing.T) {
+	var collisions []*sbckey.CollisionError
+	detector := sbckey.NewCollisionDetector(func(err *sbckey.CollisionError) {
+		collisions = append(collisions, err)
+	})
+	// both types are formatted as "template.Template"
+	text := sbckey.DetectCollisions(detector, sbckey.NatsDefaultKeyBuilder[template.Template]())
+	html := sbckey.DetectCollisions(detector, sbckey.NatsDefaultKeyBuilder[htmltemplate.Template]())
+	text.BuildKey(template.Template{})
+	text.BuildKey(template.Template{})
+	html.BuildKey(htmltemplate.Template{})
+	if len(collisions) != 1 {
+		t.Fatalf("Expected 1 collision, got %d", len(collisions))
+	}
+	if collisions[0].Existing != reflect.TypeFor[template.Template]() || collisions[0].New != reflect.TypeFor[htmltemplate.Template]() {
+		t.Errorf("unexpected collision %v", collisions[0])
+	}
+}
+
+func TestDetectCollisionsNoCollisionWithTypeKeys(t *testing.T) {
+	detector := sbckey.NewCollisionDetector(nil)
+	sbckey.DetectCollisions(detector, sbckey.NatsTypeKeyBuilder[template.Template]()).BuildKey(template.Template{})
+	sbckey.DetectCollisions(detector, sbckey.NatsTypeKeyBuilder[htmltemplate.Template]()).BuildKey(htmltemplate.Template{})
+}
+
+func TestDetectCollisionsErrorByDefault(t *testing.T) {
+	detector := sbckey.NewCollisionDetector(nil)
+	ctx := context.Background()
+	text := sbckey.DetectCollisions(detector, sbckey.DefaultKeyBuilder[template.Template]())
+	if _, err := sbc.BuildKeyContext(ctx, text, template.Template{}); err != nil {
+		t.Fatal(err)
+	}
+	html := sbckey.DetectCollisions(detector, sbckey.DefaultKeyBuilder[htmltemplate.Template]())
+	_, err := sbc.BuildKeys(ctx, html, htmltemplate.Template{})
+	var cerr *sbckey.CollisionError
+	if !errors.As(err, &cerr) || cerr.Key != "template.Template" {
+		t.Errorf("Expected CollisionError, got %v", err)
+	}
+	if key := html.BuildKey(htmltemplate.Template{}); key != "" {
+		t.Errorf("Expected an empty key, got '%s'", key)
+	}
+}
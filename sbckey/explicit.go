@@ -0,0 +1,121 @@
+package sbckey
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+)
+
+// TagName is the name of the struct tag that configures the key of a config type.
+const TagName = "sbc"
+
+// ConfigKeyer is implemented by config types that define their own key.
+type ConfigKeyer interface {
+
+	// ConfigKey returns the key of the config.
+	ConfigKey() string
+}
+
+// ExplicitKeyBuilder returns a KeyBuilder that uses the key defined by the config type itself,
+// so the key stays stable when the type is renamed or moved.
+//
+// The key is taken from the first available source:
+//   - the ConfigKey() method of T (or of *T);
+//   - the `sbc:"key=..."` tag of any field of T, usually a blank marker field;
+//   - the fallback KeyBuilder, DefaultKeyBuilder when the fallback is nil.
+//
+// Example usage:
+//
+//	type Limits struct {
+//		_   struct{} `sbc:"key=payments/limits"`
+//		Max int      `json:"max"`
+//	}
+//
+//	builder := ExplicitKeyBuilder[Limits](nil)
+//	key := builder.BuildKey(Limits{}) // key = "payments/limits"
+func ExplicitKeyBuilder[T any](fallback sbc.KeyBuilder[T]) sbc.KeyBuilder[T] {
+	if fallback == nil {
+		fallback = DefaultKeyBuilder[T]()
+	}
+	tagKey, hasTag := keyFromTag(reflect.TypeFor[T]())
+	return explicitKeyBuilder[T]{fallback: fallback, tagKey: tagKey, hasTag: hasTag}
+}
+
+// explicitKeyBuilder is a MultiKeyBuilder that uses the key defined by the config type, or the keys of
+// the fallback KeyBuilder, so the errors and the aliases of the fallback are kept.
+type explicitKeyBuilder[T any] struct {
+	fallback sbc.KeyBuilder[T]
+	tagKey   string
+	hasTag   bool
+}
+
+// BuildKey returns the key built with a background context, or an empty key on error.
+func (e explicitKeyBuilder[T]) BuildKey(t T) string {
+	key, err := e.BuildKeyContext(context.Background(), t)
+	if err != nil {
+		return ""
+	}
+	return key
+}
+
+// BuildKeyContext returns the key defined by the config type, or the key of the fallback KeyBuilder.
+func (e explicitKeyBuilder[T]) BuildKeyContext(ctx context.Context, t T) (string, error) {
+	if key, ok := e.explicitKey(t); ok {
+		return key, nil
+	}
+	return sbc.BuildKeyContext(ctx, e.fallback, t)
+}
+
+// BuildKeys returns the key defined by the config type, or the keys of the fallback KeyBuilder.
+func (e explicitKeyBuilder[T]) BuildKeys(ctx context.Context, t T) ([]string, error) {
+	if key, ok := e.explicitKey(t); ok {
+		return []string{key}, nil
+	}
+	return sbc.BuildKeys(ctx, e.fallback, t)
+}
+
+// explicitKey returns the key of the ConfigKey() method or of the tag.
+func (e explicitKeyBuilder[T]) explicitKey(t T) (string, bool) {
+	if key, ok := keyFromMethod(t); ok {
+		return key, true
+	}
+	return e.tagKey, e.hasTag
+}
+
+// keyFromMethod returns the key of the ConfigKey() method of the value or of a pointer to it.
+func keyFromMethod[T any](t T) (string, bool) {
+	rv := reflect.ValueOf(&t).Elem()
+	if rv.Kind() == reflect.Pointer && rv.IsNil() {
+		// call the method on a zero value to avoid a nil dereference in value receivers
+		rv = reflect.New(rv.Type().Elem())
+	}
+	if k, ok := rv.Interface().(ConfigKeyer); ok {
+		return k.ConfigKey(), true
+	}
+	if rv.CanAddr() {
+		if k, ok := rv.Addr().Interface().(ConfigKeyer); ok {
+			return k.ConfigKey(), true
+		}
+	}
+	return "", false
+}
+
+// keyFromTag returns the key of the first struct field with the `sbc:"key=..."` tag.
+func keyFromTag(t reflect.Type) (string, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return "", false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		for _, opt := range strings.Split(t.Field(i).Tag.Get(TagName), ",") {
+			if key, ok := strings.CutPrefix(strings.TrimSpace(opt), "key="); ok && key != "" {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
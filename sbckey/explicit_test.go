@@ -0,0 +1,78 @@
+package sbckey_test
+
+import (
+	"testing"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbckey"
+)
+
+type taggedConfig struct {
+	_   struct{} `sbc:"key=payments/limits"`
+	Max int      `json:"max"`
+}
+
+type methodConfig struct {
+	Tenant string
+}
+
+func (c methodConfig) ConfigKey() string {
+	return "limits/" + c.Tenant
+}
+
+type pointerMethodConfig struct{}
+
+func (c *pointerMethodConfig) ConfigKey() string {
+	return "pointer/method"
+}
+
+type methodOverTagConfig struct {
+	_ struct{} `sbc:"key=from/tag"`
+}
+
+func (methodOverTagConfig) ConfigKey() string {
+	return "from/method"
+}
+
+func TestExplicitKeyBuilderWithTag(t *testing.T) {
+	result := sbckey.ExplicitKeyBuilder[taggedConfig](nil).BuildKey(taggedConfig{})
+	if result != "payments/limits" {
+		t.Errorf("Expected 'payments/limits', got '%s'", result)
+	}
+	result = sbckey.ExplicitKeyBuilder[*taggedConfig](nil).BuildKey(nil)
+	if result != "payments/limits" {
+		t.Errorf("Expected 'payments/limits', got '%s'", result)
+	}
+}
+
+func TestExplicitKeyBuilderWithMethod(t *testing.T) {
+	result := sbckey.ExplicitKeyBuilder[methodConfig](nil).BuildKey(methodConfig{Tenant: "42"})
+	if result != "limits/42" {
+		t.Errorf("Expected 'limits/42', got '%s'", result)
+	}
+	result = sbckey.ExplicitKeyBuilder[*methodConfig](nil).BuildKey(nil)
+	if result != "limits/" {
+		t.Errorf("Expected 'limits/', got '%s'", result)
+	}
+	result = sbckey.ExplicitKeyBuilder[pointerMethodConfig](nil).BuildKey(pointerMethodConfig{})
+	if result != "pointer/method" {
+		t.Errorf("Expected 'pointer/method', got '%s'", result)
+	}
+}
+
+func TestExplicitKeyBuilderMethodOverTag(t *testing.T) {
+	result := sbckey.ExplicitKeyBuilder[methodOverTagConfig](nil).BuildKey(methodOverTagConfig{})
+	if result != "from/method" {
+		t.Errorf("Expected 'from/method', got '%s'", result)
+	}
+}
+
+func TestExplicitKeyBuilderFallback(t *testing.T) {
+	result := sbckey.ExplicitKeyBuilder[int](nil).BuildKey(1)
+	if result != "int" {
+		t.Errorf("Expected 'int', got '%s'", result)
+	}
+	result = sbckey.ExplicitKeyBuilder[int](sbckey.ConsulDefaultKeyBuilder[int]("prefix/")).BuildKey(1)
+	if result != "prefix/int" {
+		t.Errorf("Expected 'prefix/int', got '%s'", result)
+	}
+}
@@ -0,0 +1,234 @@
+package sbckey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+)
+
+// TypePlaceholder is the placeholder that is always resolved to the escaped name of the config type
+// without the package, e.g. "Config" for main.Config.
+const TypePlaceholder = "type"
+
+// ErrUnresolvedPlaceholder is an error that is returned when a template placeholder has no value.
+var ErrUnresolvedPlaceholder = errors.New("unresolved placeholder")
+
+// placeholderRe matches a single placeholder of a key template, e.g. "{env}".
+var placeholderRe = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// placeholderNameRe matches a valid placeholder name.
+var placeholderNameRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// TemplateKeyBuilder is a KeyBuilder that builds keys from a template like "{env}/{region}/{service}/{type}".
+//
+// Placeholders are resolved from the first available source:
+//   - the sbc.KeyParams of the subscription, see sbc.Subscriber.SubscribeWithParams;
+//   - the values stored in the context with ContextWithTemplateValues;
+//   - the values set with the WithTemplateValue option;
+//   - the environment variables, named by the WithTemplateEnv option or by the WithTemplateEnvPrefix
+//     option followed by the upper-cased placeholder name;
+//   - the built-in {type} placeholder.
+//
+// The subscription builds keys with BuildKeyContext, so unresolved placeholders and keys rejected by
+// the validator fail the subscription. BuildKey returns an empty key in these cases.
+type TemplateKeyBuilder[T any] struct {
+	template  string
+	typeName  string
+	values    map[string]string
+	envs      map[string]string
+	envPrefix string
+	validate  func(key string) error
+}
+
+// ensure TemplateKeyBuilder implements sbc.ContextKeyBuilder
+var _ sbc.ContextKeyBuilder[struct{}] = (*TemplateKeyBuilder[struct{}])(nil)
+
+// NewTemplateKeyBuilder creates a new TemplateKeyBuilder. It returns an error if the template is malformed.
+//
+// Example usage:
+//
+//	builder, err := NewTemplateKeyBuilder[Config]("{env}/{region}/{type}",
+//		WithTemplateValue("region", "eu"),
+//		WithTemplateEnv("env", "APP_ENV"),
+//		WithTemplateValidator(ValidateConsulKey),
+//	)
+//	key := builder.BuildKey(Config{}) // key = "prod/eu/Config" when APP_ENV=prod
+func NewTemplateKeyBuilder[T any](template string, opts ...TemplateOpt) (*TemplateKeyBuilder[T], error) {
+	if err := checkTemplate(template); err != nil {
+		return nil, err
+	}
+	o := templateOpts{values: map[string]string{}, envs: map[string]string{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &TemplateKeyBuilder[T]{
+		template:  template,
+		typeName:  EscapeKey(typeName(reflect.TypeFor[T]())),
+		values:    o.values,
+		envs:      o.envs,
+		envPrefix: o.envPrefix,
+		validate:  o.validate,
+	}, nil
+}
+
+// BuildKey builds the key without a context. It returns an empty key if a placeholder is unresolved
+// or the key is invalid, use BuildKeyContext to get the error.
+func (b *TemplateKeyBuilder[T]) BuildKey(t T) string {
+	key, err := b.BuildKeyContext(context.Background(), t)
+	if err != nil {
+		return ""
+	}
+	return key
+}
+
+// BuildKeyContext builds the key using the values stored in the context and validates it.
+func (b *TemplateKeyBuilder[T]) BuildKeyContext(ctx context.Context, _ T) (string, error) {
+	key, err := b.resolve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to build key from template '%s': %w", b.template, err)
+	}
+	if b.validate != nil {
+		if err := b.validate(key); err != nil {
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// resolve replaces the placeholders of the template, returning the joined errors of unresolved placeholders.
+// The unresolved placeholders are left in the key, which must not be used when there is an error.
+func (b *TemplateKeyBuilder[T]) resolve(ctx context.Context) (string, error) {
+	ctxValues := sbc.KeyParamsFromContext(ctx)
+	if values, ok := ctx.Value(templateValuesKey{}).(map[string]string); ok {
+		for k, v := range values {
+			// the subscription params take precedence
+			if _, ok := ctxValues[k]; !ok {
+				ctxValues[k] = v
+			}
+		}
+	}
+	var errs []error
+	key := placeholderRe.ReplaceAllStringFunc(b.template, func(m string) string {
+		name := m[1 : len(m)-1]
+		if v, ok := b.lookup(ctxValues, name); ok {
+			return v
+		}
+		errs = append(errs, fmt.Errorf("{%s}: %w", name, ErrUnresolvedPlaceholder))
+		return m
+	})
+	return key, errors.Join(errs...)
+}
+
+// lookup returns the value of the placeholder.
+func (b *TemplateKeyBuilder[T]) lookup(ctxValues map[string]string, name string) (string, bool) {
+	if v, ok := ctxValues[name]; ok {
+		return v, true
+	}
+	if v, ok := b.values[name]; ok {
+		return v, true
+	}
+	if env, ok := b.envs[name]; ok {
+		if v, ok := os.LookupEnv(env); ok {
+			return v, true
+		}
+	}
+	if b.envPrefix != "" {
+		if v, ok := os.LookupEnv(b.envPrefix + strings.ToUpper(name)); ok {
+			return v, true
+		}
+	}
+	if name == TypePlaceholder {
+		return b.typeName, true
+	}
+	return "", false
+}
+
+// checkTemplate checks that every placeholder of the template has a valid name and that braces are balanced.
+func checkTemplate(template string) error {
+	for _, m := range placeholderRe.FindAllStringSubmatch(template, -1) {
+		if !placeholderNameRe.MatchString(m[1]) {
+			return fmt.Errorf("invalid placeholder name '%s' in template '%s'", m[1], template)
+		}
+	}
+	if rest := placeholderRe.ReplaceAllString(template, ""); strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("unbalanced braces in template '%s'", template)
+	}
+	return nil
+}
+
+// typeName returns the name of the type without the package, dereferencing pointers.
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Name() != "" {
+		// drop the type arguments of generic types
+		name, _, _ := strings.Cut(t.Name(), "[")
+		return name
+	}
+	return t.String()
+}
+
+// templateValuesKey is the context key of the template values.
+type templateValuesKey struct{}
+
+// ContextWithTemplateValues returns a copy of the context that carries the given template values.
+// The values are merged with the values already stored in the context, the new ones take precedence.
+func ContextWithTemplateValues(ctx context.Context, values map[string]string) context.Context {
+	merged := make(map[string]string)
+	if existing, ok := ctx.Value(templateValuesKey{}).(map[string]string); ok {
+		for k, v := range existing {
+			merged[k] = v
+		}
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, templateValuesKey{}, merged)
+}
+
+// TemplateOpt is a function type that configures a TemplateKeyBuilder.
+type TemplateOpt func(*templateOpts)
+
+// templateOpts is a struct that holds the options of a TemplateKeyBuilder.
+type templateOpts struct {
+	values    map[string]string
+	envs      map[string]string
+	envPrefix string
+	validate  func(key string) error
+}
+
+// WithTemplateValue sets the value of a placeholder.
+func WithTemplateValue(name, value string) TemplateOpt {
+	return func(o *templateOpts) {
+		o.values[name] = value
+	}
+}
+
+// WithTemplateEnv resolves a placeholder from the given environment variable.
+func WithTemplateEnv(name, envVar string) TemplateOpt {
+	return func(o *templateOpts) {
+		o.envs[name] = envVar
+	}
+}
+
+// WithTemplateEnvPrefix resolves every placeholder from the environment variable named by the prefix followed
+// by the upper-cased placeholder name, e.g. "SBC_REGION" for the placeholder {region} and the prefix "SBC_".
+func WithTemplateEnvPrefix(prefix string) TemplateOpt {
+	return func(o *templateOpts) {
+		o.envPrefix = prefix
+	}
+}
+
+// WithTemplateValidator sets the function that validates built keys, e.g. ValidateNatsKey or ValidateConsulKey.
+func WithTemplateValidator(validate func(key string) error) TemplateOpt {
+	return func(o *templateOpts) {
+		o.validate = validate
+	}
+}
@@ -0,0 +1,86 @@
+package sbckey_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+	"github.com/Autodoc-Technology/streaming-based-config/sbckey"
+)
+
+func TestNewTemplateKeyBuilderMalformed(t *testing.T) {
+	for _, tmpl := range []string{"{env", "env}/{type}", "{}/{type}", "{1env}", "{env/{type}}"} {
+		if _, err := sbckey.NewTemplateKeyBuilder[int](tmpl); err == nil {
+			t.Errorf("template '%s': expected error", tmpl)
+		}
+	}
+}
+
+func TestTemplateKeyBuilderResolvesSources(t *testing.T) {
+	t.Setenv("TEST_APP_ENV", "prod")
+	t.Setenv("SBC_SERVICE", "orders")
+	builder, err := sbckey.NewTemplateKeyBuilder[GenericConfig[int]]("{env}/{region}/{service}/{type}",
+		sbckey.WithTemplateValue("region", "eu"),
+		sbckey.WithTemplateEnv("env", "TEST_APP_ENV"),
+		sbckey.WithTemplateEnvPrefix("SBC_"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := builder.BuildKey(GenericConfig[int]{}); result != "prod/eu/orders/GenericConfig" {
+		t.Errorf("Expected 'prod/eu/orders/GenericConfig', got '%s'", result)
+	}
+	ctx := sbckey.ContextWithTemplateValues(context.Background(), map[string]string{"region": "us"})
+	result, err := builder.BuildKeyContext(ctx, GenericConfig[int]{})
+	if err != nil || result != "prod/us/orders/GenericConfig" {
+		t.Errorf("Expected 'prod/us/orders/GenericConfig', got '%s', %v", result, err)
+	}
+}
+
+func TestTemplateKeyBuilderUnresolved(t *testing.T) {
+	builder, _ := sbckey.NewTemplateKeyBuilder[int]("{env}/{type}")
+	if result := builder.BuildKey(0); result != "" {
+		t.Errorf("Expected an empty key, got '%s'", result)
+	}
+	_, err := builder.BuildKeyContext(context.Background(), 0)
+	if !errors.Is(err, sbckey.ErrUnresolvedPlaceholder) {
+		t.Errorf("Expected ErrUnresolvedPlaceholder, got %v", err)
+	}
+}
+
+func TestTemplateKeyBuilderValidation(t *testing.T) {
+	builder, _ := sbckey.NewTemplateKeyBuilder[int]("{env}.{type}", sbckey.WithTemplateValidator(sbckey.ValidateNatsKey))
+	ctx := sbckey.ContextWithTemplateValues(context.Background(), map[string]string{"env": "prod eu"})
+	if _, err := builder.BuildKeyContext(ctx, 0); !errors.Is(err, sbckey.ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+	ctx = sbckey.ContextWithTemplateValues(ctx, map[string]string{"env": "prod"})
+	if result, err := builder.BuildKeyContext(ctx, 0); err != nil || result != "prod.int" {
+		t.Errorf("Expected 'prod.int', got '%s', %v", result, err)
+	}
+}
+
+func TestTemplateKeyBuilderWithKeyParams(t *testing.T) {
+	builder, _ := sbckey.NewTemplateKeyBuilder[int]("limits/tenant-{tenant}", sbckey.WithTemplateValue("tenant", "0"))
+	ctx := sbckey.ContextWithTemplateValues(context.Background(), map[string]string{"tenant": "1"})
+	ctx = sbc.ContextWithKeyParams(ctx, sbc.KeyParams{"tenant": "42"})
+	result, err := builder.BuildKeyContext(ctx, 0)
+	if err != nil || result != "limits/tenant-42" {
+		t.Errorf("Expected 'limits/tenant-42', got '%s', %v", result, err)
+	}
+}
+
+func TestTemplateKeyBuilderUnresolvedWrapped(t *testing.T) {
+	template, _ := sbckey.NewTemplateKeyBuilder[int]("{env}/{type}")
+	detector := sbckey.NewCollisionDetector(func(err *sbckey.CollisionError) { t.Errorf("unexpected collision %v", err) })
+	builders := map[string]sbc.KeyBuilder[int]{
+		"collisions": sbckey.DetectCollisions[int](detector, template),
+		"explicit":   sbckey.ExplicitKeyBuilder[int](template),
+	}
+	for name, kb := range builders {
+		if _, err := sbc.BuildKeyContext(context.Background(), kb, 0); !errors.Is(err, sbckey.ErrUnresolvedPlaceholder) {
+			t.Errorf("%s: expected ErrUnresolvedPlaceholder, got %v", name, err)
+		}
+	}
+}
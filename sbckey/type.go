@@ -0,0 +1,110 @@
+package sbckey
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+)
+
+// TypeKeyBuilder returns a KeyBuilder that uses the identity of type T as the key.
+//
+// Unlike DefaultKeyBuilder, the identity contains the full import path of every named type, so types with the
+// same name in different packages get different keys:
+//
+//	builder := TypeKeyBuilder[config.Config]()
+//	key := builder.BuildKey(config.Config{}) // key = "github.com/acme/billing/config.Config"
+func TypeKeyBuilder[T any]() sbc.KeyBuilder[T] {
+	key := TypeIdentity(reflect.TypeFor[T]())
+	return sbc.KeyBuilderFunc[T](func(T) string {
+		return key
+	})
+}
+
+// NatsTypeKeyBuilder returns a KeyBuilder that uses the escaped identity of type T as the key.
+// Characters that are not allowed in NATS KV keys are escaped, see EscapeKey.
+func NatsTypeKeyBuilder[T any]() sbc.KeyBuilder[T] {
+	key := EscapeKey(TypeIdentity(reflect.TypeFor[T]()))
+	return sbc.KeyBuilderFunc[T](func(T) string {
+		return key
+	})
+}
+
+// ConsulTypeKeyBuilder returns a KeyBuilder that uses the prefix and the escaped identity of type T as the key.
+// The slashes of the import path become folders in Consul KV, other unsafe characters are escaped, see EscapeKey.
+//
+//	builder := ConsulTypeKeyBuilder[config.Config]("prod/")
+//	key := builder.BuildKey(config.Config{}) // key = "prod/github.com/acme/billing/config.Config"
+func ConsulTypeKeyBuilder[T any](prefix string) sbc.KeyBuilder[T] {
+	key := prefix + EscapeKey(TypeIdentity(reflect.TypeFor[T]()))
+	return sbc.KeyBuilderFunc[T](func(T) string {
+		return strings.TrimPrefix(key, "/")
+	})
+}
+
+// TypeIdentity returns a string that identifies the type, using full import paths for all named types,
+// including the type arguments of generic types.
+//
+// Example: "github.com/acme/billing/config.Config[map[string]*net/http.Client]".
+func TypeIdentity(t reflect.Type) string {
+	if t == nil {
+		return "nil"
+	}
+	if t.Name() != "" {
+		// type arguments in the name already use full import paths, drop the spaces of inline struct types
+		name := strings.ReplaceAll(t.Name(), " ", "")
+		if t.PkgPath() == "" {
+			return name
+		}
+		return t.PkgPath() + "." + name
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + TypeIdentity(t.Elem())
+	case reflect.Slice:
+		return "[]" + TypeIdentity(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), TypeIdentity(t.Elem()))
+	case reflect.Map:
+		return "map[" + TypeIdentity(t.Key()) + "]" + TypeIdentity(t.Elem())
+	case reflect.Chan:
+		switch t.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan" + TypeIdentity(t.Elem())
+		case reflect.SendDir:
+			return "chan<-" + TypeIdentity(t.Elem())
+		default:
+			return "chan" + TypeIdentity(t.Elem())
+		}
+	default:
+		// unnamed structs, functions and interfaces
+		return strings.ReplaceAll(t.String(), " ", "")
+	}
+}
+
+// escapeChar is the character that starts an escape sequence in escaped keys.
+const escapeChar = '='
+
+// EscapeKey escapes every byte that is not a letter, a digit or one of "-_./" as "=XX", where XX is
+// the hexadecimal byte value, "=" itself included. The result is valid both as a NATS KV key and as
+// a Consul KV path, and the escaping is reversible, so different inputs never produce the same key.
+// Leading, trailing and repeated dots are escaped too, as NATS does not allow empty subject tokens.
+func EscapeKey(key string) string {
+	var sb strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if isSafeKeyChar(c) && !(c == '.' && (i == 0 || i == len(key)-1 || key[i-1] == '.')) {
+			sb.WriteByte(c)
+			continue
+		}
+		_, _ = fmt.Fprintf(&sb, "%c%02X", escapeChar, c)
+	}
+	return sb.String()
+}
+
+// isSafeKeyChar reports whether the byte can be used in a key without escaping.
+func isSafeKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || c == '/'
+}
@@ -0,0 +1,78 @@
+package sbckey_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbckey"
+)
+
+type Duration int
+
+type GenericConfig[T any] struct {
+	Value T
+}
+
+const testPkg = "github.com/Autodoc-Technology/streaming-based-config/sbckey_test"
+
+func TestTypeIdentity(t *testing.T) {
+	cases := []struct {
+		t        reflect.Type
+		expected string
+	}{
+		{reflect.TypeFor[int](), "int"},
+		{reflect.TypeFor[time.Duration](), "time.Duration"},
+		{reflect.TypeFor[Duration](), testPkg + ".Duration"},
+		{reflect.TypeFor[*Duration](), "*" + testPkg + ".Duration"},
+		{reflect.TypeFor[map[string][]*http.Client](), "map[string][]*net/http.Client"},
+		{reflect.TypeFor[[2]Duration](), "[2]" + testPkg + ".Duration"},
+		{reflect.TypeFor[<-chan Duration](), "<-chan" + testPkg + ".Duration"},
+		{reflect.TypeFor[GenericConfig[map[string]*http.Client]](), testPkg + ".GenericConfig[map[string]*net/http.Client]"},
+		{reflect.TypeFor[GenericConfig[struct{ A int }]](), testPkg + ".GenericConfig[struct{Aint}]"},
+	}
+	for _, c := range cases {
+		if result := sbckey.TypeIdentity(c.t); result != c.expected {
+			t.Errorf("Expected '%s', got '%s'", c.expected, result)
+		}
+	}
+}
+
+func TestTypeKeyBuilderDistinguishesPackages(t *testing.T) {
+	local := sbckey.TypeKeyBuilder[Duration]().BuildKey(0)
+	std := sbckey.TypeKeyBuilder[time.Duration]().BuildKey(0)
+	if local == std {
+		t.Errorf("Expected different keys, got '%s'", local)
+	}
+}
+
+func TestNatsTypeKeyBuilder(t *testing.T) {
+	result := sbckey.NatsTypeKeyBuilder[GenericConfig[*Duration]]().BuildKey(GenericConfig[*Duration]{})
+	expected := testPkg + ".GenericConfig=5B=2A" + testPkg + ".Duration=5D"
+	if result != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, result)
+	}
+}
+
+func TestConsulTypeKeyBuilder(t *testing.T) {
+	result := sbckey.ConsulTypeKeyBuilder[Duration]("/prod/").BuildKey(0)
+	if result != "prod/"+testPkg+".Duration" {
+		t.Errorf("Expected 'prod/%s.Duration', got '%s'", testPkg, result)
+	}
+}
+
+func TestEscapeKey(t *testing.T) {
+	cases := map[string]string{
+		"a-b_c/d.e": "a-b_c/d.e",
+		"a=b":       "a=3Db",
+		"a=3Db":     "a=3D3Db",
+		"[]*x y":    "=5B=5D=2Ax=20y",
+		".a..b.":    "=2Ea.=2Eb=2E",
+	}
+	for in, expected := range cases {
+		if result := sbckey.EscapeKey(in); result != expected {
+			t.Errorf("EscapeKey(%q): expected '%s', got '%s'", in, expected, result)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package sbckey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidKey is an error that is returned when a key is not valid for a transport.
+var ErrInvalidKey = errors.New("invalid key")
+
+// ValidateNatsKey checks that the key is a valid NATS KV key: it consists of letters, digits and "-/_=."
+// characters only, and it has no empty tokens (leading, trailing or repeated dots).
+func ValidateNatsKey(key string) error {
+	if !natsKeyValid(key) || strings.Contains(key, "..") {
+		return fmt.Errorf("key '%s' is not a valid NATS key: %w", key, ErrInvalidKey)
+	}
+	return nil
+}
+
+// ValidateConsulKey checks that the key is a valid Consul KV path: it is not empty, it does not start with
+// a slash, it has no empty segments, and it contains no whitespace or control characters.
+func ValidateConsulKey(key string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("key is empty: %w", ErrInvalidKey)
+	case strings.HasPrefix(key, "/"):
+		return fmt.Errorf("key '%s' starts with a slash: %w", key, ErrInvalidKey)
+	case strings.Contains(key, "//"):
+		return fmt.Errorf("key '%s' has an empty segment: %w", key, ErrInvalidKey)
+	case strings.ContainsFunc(key, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }):
+		return fmt.Errorf("key '%s' contains whitespace or control characters: %w", key, ErrInvalidKey)
+	}
+	return nil
+}
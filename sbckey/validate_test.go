@@ -0,0 +1,38 @@
+package sbckey_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbckey"
+)
+
+func TestValidateNatsKey(t *testing.T) {
+	valid := []string{"config", "prod.eu.orders", "prod/eu/orders", "a-b_c=d"}
+	invalid := []string{"", ".config", "config.", "prod..eu", "prod eu", "prod*", "prod>"}
+	for _, key := range valid {
+		if err := sbckey.ValidateNatsKey(key); err != nil {
+			t.Errorf("key '%s': unexpected error %v", key, err)
+		}
+	}
+	for _, key := range invalid {
+		if err := sbckey.ValidateNatsKey(key); !errors.Is(err, sbckey.ErrInvalidKey) {
+			t.Errorf("key '%s': expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
+
+func TestValidateConsulKey(t *testing.T) {
+	valid := []string{"config", "prod/eu/orders", "prod/eu/main.Config[string]"}
+	invalid := []string{"", "/prod/eu", "prod//eu", "prod/e u", "prod\teu"}
+	for _, key := range valid {
+		if err := sbckey.ValidateConsulKey(key); err != nil {
+			t.Errorf("key '%s': unexpected error %v", key, err)
+		}
+	}
+	for _, key := range invalid {
+		if err := sbckey.ValidateConsulKey(key); !errors.Is(err, sbckey.ErrInvalidKey) {
+			t.Errorf("key '%s': expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
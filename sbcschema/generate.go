@@ -0,0 +1,142 @@
+package sbcschema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// tagName is the struct tag with the sbc options of a field, the same tag sbckey reads the key from.
+// The "required" option marks a property as required: `sbc:"required"`.
+const tagName = "sbc"
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// For generates the schema of the JSON representation of type T.
+//
+// Struct fields are mapped to properties following the encoding/json rules: the `json` tag names the
+// property, "-" skips the field, and embedded structs are flattened. Like encoding/json, which accepts
+// partial objects, the properties are optional unless the field is marked with the `sbc:"required"` tag.
+// Types with a custom JSON marshaller accept any value.
+func For[T any]() *Schema {
+	return Generate(reflect.TypeFor[T]())
+}
+
+// Generate generates the schema of the JSON representation of the given type.
+func Generate(t reflect.Type) *Schema {
+	return generate(t, map[reflect.Type]bool{})
+}
+
+// generate generates the schema of the type, visiting tracks the struct types on the current path
+// to stop on recursive types.
+func generate(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if t == timeType {
+		return &Schema{Type: Types{TypeString}}
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return &Schema{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: Types{TypeString}}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Types{TypeBoolean}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: Types{TypeInteger}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		zero := 0.0
+		return &Schema{Type: Types{TypeInteger}, Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{TypeNumber}}
+	case reflect.String:
+		return &Schema{Type: Types{TypeString}}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is encoded as a base64 string
+			return &Schema{Type: Types{TypeString, TypeNull}}
+		}
+		return &Schema{Type: Types{TypeArray, TypeNull}, Items: generate(t.Elem(), visiting)}
+	case reflect.Array:
+		n := t.Len()
+		return &Schema{Type: Types{TypeArray}, Items: generate(t.Elem(), visiting), MinItems: &n, MaxItems: &n}
+	case reflect.Map:
+		return &Schema{Type: Types{TypeObject, TypeNull}, AdditionalProperties: generate(t.Elem(), visiting)}
+	case reflect.Pointer:
+		s := generate(t.Elem(), visiting)
+		if len(s.Type) > 0 && !containsType(s.Type, TypeNull) {
+			s.Type = append(s.Type, TypeNull)
+		}
+		return s
+	case reflect.Struct:
+		if visiting[t] {
+			return &Schema{}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		s := &Schema{Type: Types{TypeObject}, Properties: map[string]*Schema{}}
+		addFields(s, t, visiting)
+		return s
+	default:
+		// interfaces accept any value, channels and functions can not be encoded at all
+		return &Schema{}
+	}
+}
+
+// addFields adds the properties of the struct fields to the object schema.
+func addFields(s *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(s, ft, visiting)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = generate(f.Type, visiting)
+		if hasOption(f.Tag.Get(tagName), "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// hasOption reports whether the comma-separated tag options contain the given option.
+func hasOption(opts, option string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// containsType reports whether the types contain the given type.
+func containsType(types Types, t string) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
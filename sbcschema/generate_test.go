@@ -0,0 +1,73 @@
+package sbcschema_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbcschema"
+)
+
+type generateBase struct {
+	ID string `json:"id" sbc:"required"`
+}
+
+type generateConfig struct {
+	generateBase
+	Name     string            `json:"name" sbc:"required"`
+	Port     uint16            `json:"port"`
+	Ratio    float64           `json:"ratio,omitempty"`
+	Hosts    []string          `json:"hosts"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Timeout  time.Time         `json:"timeout,omitempty"`
+	Parent   *generateConfig   `json:"parent" sbc:"required"`
+	Skipped  string            `json:"-"`
+	Untagged bool              `sbc:"key=ignored,required"`
+	internal int
+}
+
+func TestForGeneratesStructSchema(t *testing.T) {
+	b, err := json.Marshal(sbcschema.For[generateConfig]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"type":"object","properties":{` +
+		`"Untagged":{"type":"boolean"},` +
+		`"hosts":{"type":["array","null"],"items":{"type":"string"}},` +
+		`"id":{"type":"string"},` +
+		`"labels":{"type":["object","null"],"additionalProperties":{"type":"string"}},` +
+		`"name":{"type":"string"},` +
+		`"parent":{},` +
+		`"port":{"type":"integer","minimum":0},` +
+		`"ratio":{"type":"number"},` +
+		`"timeout":{"type":"string"}},` +
+		`"required":["id","name","parent","Untagged"]}`
+	if string(b) != expected {
+		t.Errorf("unexpected schema\n%s\nexpected\n%s", b, expected)
+	}
+}
+
+func TestForValidatesEncodedValue(t *testing.T) {
+	schema := sbcschema.For[generateConfig]()
+	b, _ := json.Marshal(generateConfig{Name: "app", Hosts: []string{"a"}})
+	if err := schema.Validate(b); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := schema.Validate([]byte(`{"id": "1", "name": "app", "port": -1, "parent": null, "Untagged": false}`)); err == nil {
+		t.Error("Expected error for negative port")
+	}
+}
+
+func TestForOptionalByDefault(t *testing.T) {
+	// a partial object that encoding/json accepts is valid unless a required field is missing
+	schema := sbcschema.For[generateConfig]()
+	if err := schema.Validate([]byte(`{"id": "1", "name": "app", "parent": null, "Untagged": true}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := schema.Validate([]byte(`{"id": "1", "parent": null, "Untagged": true}`)); err == nil {
+		t.Error("Expected error for missing name")
+	}
+	if err := sbcschema.For[validatingConfig]().Validate([]byte(`{}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
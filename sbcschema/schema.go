@@ -0,0 +1,319 @@
+package sbcschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSON Schema type names.
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeNull    = "null"
+)
+
+// Schema is a JSON Schema document.
+//
+// Only the validation keywords that are useful for configs are supported: type, properties, required,
+// additionalProperties, items, enum, not, minimum, maximum, minLength, maxLength, pattern, minItems and maxItems.
+// Unknown keywords are ignored. The boolean schemas true and false are accepted as well.
+type Schema struct {
+	Type                 Types              `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Not                  *Schema            `json:"not,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Parse parses a JSON Schema document.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if err := s.Compile(); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	return &s, nil
+}
+
+// UnmarshalJSON decodes a schema, the boolean schema true is an empty schema and false is {"not": {}}.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{Not: &Schema{}}
+		return nil
+	}
+	// schemaAlias drops the methods of Schema to avoid the recursion
+	type schemaAlias Schema
+	var alias schemaAlias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*s = Schema(alias)
+	return nil
+}
+
+// Compile compiles the patterns of the schema and of all its subschemas. Parse and NewValidatingEncoder
+// call it, a schema built in code must be compiled before it is used with Validate or ValidateValue,
+// otherwise its patterns never match.
+func (s *Schema) Compile() error {
+	if s == nil {
+		return nil
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	for _, p := range s.Properties {
+		if err := p.Compile(); err != nil {
+			return err
+		}
+	}
+	for _, sub := range []*Schema{s.AdditionalProperties, s.Items, s.Not} {
+		if err := sub.Compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Types is the value of the "type" keyword, encoded as a string when it holds a single type.
+type Types []string
+
+// MarshalJSON encodes a single type as a string and several types as an array.
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON decodes a type given either as a string or as an array of strings.
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+// FieldError describes a single validation failure.
+type FieldError struct {
+
+	// Pointer is the JSON pointer (RFC 6901) to the offending value, "" is the document root.
+	Pointer string
+
+	// Message describes the failure.
+	Message string
+}
+
+// Error returns the string representation of the FieldError.
+func (e FieldError) Error() string {
+	if e.Pointer == "" {
+		return "/: " + e.Message
+	}
+	return e.Pointer + ": " + e.Message
+}
+
+// ValidationError is an error that is returned when a document does not match the schema.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error returns the string representation of the ValidationError.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return "schema validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Validate validates a JSON document against the schema.
+func (s *Schema) Validate(data []byte) error {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse document: %w", err)
+	}
+	return s.ValidateValue(doc)
+}
+
+// ValidateValue validates a generic value, as produced by decoding a document into an `any`, against the schema.
+// It returns a *ValidationError listing every offending field.
+func (s *Schema) ValidateValue(doc any) error {
+	var errs []FieldError
+	s.validate(doc, "", &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// validate validates the value at the given pointer and appends the failures to errs.
+func (s *Schema) validate(v any, ptr string, errs *[]FieldError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, FieldError{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.Not != nil {
+		var notErrs []FieldError
+		s.Not.validate(v, ptr, &notErrs)
+		if len(notErrs) == 0 {
+			fail("value is not allowed")
+			return
+		}
+	}
+	if len(s.Type) > 0 && !s.matchesType(v) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), typeOf(v))
+		return
+	}
+	if len(s.Enum) > 0 && !s.inEnum(v) {
+		fail("value is not one of the allowed values")
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		s.validateObject(val, ptr, errs)
+	case []any:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			fail("expected at least %d items, got %d", *s.MinItems, len(val))
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			fail("expected at most %d items, got %d", *s.MaxItems, len(val))
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				s.Items.validate(item, fmt.Sprintf("%s/%d", ptr, i), errs)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("expected at least %d characters, got %d", *s.MinLength, n)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("expected at most %d characters, got %d", *s.MaxLength, n)
+		}
+		if s.Pattern != "" {
+			if s.pattern == nil {
+				fail("pattern '%s' is not compiled, see Schema.Compile", s.Pattern)
+			} else if !s.pattern.MatchString(val) {
+				fail("value does not match pattern '%s'", s.Pattern)
+			}
+		}
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			fail("expected at least %v, got %v", *s.Minimum, val)
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			fail("expected at most %v, got %v", *s.Maximum, val)
+		}
+	}
+}
+
+// validateObject validates the properties of an object.
+func (s *Schema) validateObject(obj map[string]any, ptr string, errs *[]FieldError) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, FieldError{Pointer: ptr + "/" + escapePointer(name), Message: "required property is missing"})
+		}
+	}
+	// iterate in a stable order to get deterministic errors
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sub, ok := s.Properties[name]
+		if !ok {
+			sub = s.AdditionalProperties
+		}
+		if sub != nil {
+			sub.validate(obj[name], ptr+"/"+escapePointer(name), errs)
+		}
+	}
+}
+
+// matchesType reports whether the value matches one of the schema types.
+func (s *Schema) matchesType(v any) bool {
+	actual := typeOf(v)
+	for _, t := range s.Type {
+		if t == actual || (t == TypeNumber && actual == TypeInteger) {
+			return true
+		}
+	}
+	return false
+}
+
+// inEnum reports whether the value equals one of the enum values.
+func (s *Schema) inEnum(v any) bool {
+	b, _ := json.Marshal(v)
+	for _, e := range s.Enum {
+		eb, _ := json.Marshal(e)
+		if bytes.Equal(b, eb) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type name of a generic value.
+func typeOf(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case string:
+		return TypeString
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return TypeInteger
+		}
+		return TypeNumber
+	case []any:
+		return TypeArray
+	case map[string]any:
+		return TypeObject
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// escapePointer escapes a reference token of a JSON pointer.
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
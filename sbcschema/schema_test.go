@@ -0,0 +1,89 @@
+package sbcschema_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbcschema"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["name", "limits"],
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+		"mode": {"enum": ["fast", "safe"]},
+		"limits": {
+			"type": "array",
+			"maxItems": 2,
+			"items": {"type": "integer", "minimum": 0}
+		}
+	},
+	"additionalProperties": false
+}`
+
+func TestParseInvalidSchema(t *testing.T) {
+	if _, err := sbcschema.Parse([]byte(`{"pattern": "("}`)); err == nil {
+		t.Fail()
+	}
+	if _, err := sbcschema.Parse([]byte(`{"type": 1}`)); err == nil {
+		t.Fail()
+	}
+}
+
+func TestSchemaValidateValid(t *testing.T) {
+	schema, err := sbcschema.Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Validate([]byte(`{"name": "app", "mode": "safe", "limits": [1, 2]}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSchemaValidateReportsPointers(t *testing.T) {
+	schema, _ := sbcschema.Parse([]byte(testSchema))
+	err := schema.Validate([]byte(`{"name": "App", "mode": "slow", "limits": [1, -1.5, 3], "extra/field": true}`))
+	var verr *sbcschema.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	expected := []string{"/extra~1field", "/limits", "/limits/1", "/mode", "/name"}
+	if len(verr.Errors) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), verr.Errors)
+	}
+	for i, fe := range verr.Errors {
+		if fe.Pointer != expected[i] {
+			t.Errorf("error %d: expected pointer '%s', got '%s'", i, expected[i], fe.Pointer)
+		}
+	}
+}
+
+func TestSchemaValidateMissingRequired(t *testing.T) {
+	schema, _ := sbcschema.Parse([]byte(testSchema))
+	err := schema.Validate([]byte(`{"name": "app"}`))
+	var verr *sbcschema.ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 1 || verr.Errors[0].Pointer != "/limits" {
+		t.Errorf("unexpected result %v", err)
+	}
+}
+
+func TestSchemaValidateRootType(t *testing.T) {
+	schema, _ := sbcschema.Parse([]byte(testSchema))
+	err := schema.Validate([]byte(`[]`))
+	if err == nil || err.Error() != "schema validation failed: /: expected object, got array" {
+		t.Errorf("unexpected result %v", err)
+	}
+}
+
+func TestSchemaValidateNotCompiled(t *testing.T) {
+	// a schema built in code is not compiled, its pattern fails instead of panicking
+	schema := &sbcschema.Schema{Pattern: "("}
+	var verr *sbcschema.ValidationError
+	if err := schema.Validate([]byte(`"app"`)); !errors.As(err, &verr) {
+		t.Errorf("Expected ValidationError, got %v", err)
+	}
+	if err := schema.Compile(); err == nil {
+		t.Error("Expected an invalid pattern error")
+	}
+}
@@ -0,0 +1,60 @@
+package sbcschema
+
+import (
+	"fmt"
+
+	"github.com/Autodoc-Technology/streaming-based-config/sbcencoder"
+)
+
+// ValidatingEncoder is an encoder decorator that validates payloads against a JSON Schema before they are
+// decoded into the target type.
+//
+// The payload is first decoded by the wrapped encoder into a generic value, so any encoder producing
+// JSON-compatible values (JSON, YAML, the MuxEncoder) can be wrapped. A payload that does not match the schema
+// fails to decode with a *ValidationError and therefore never reaches the subscription holder.
+type ValidatingEncoder struct {
+	inner  sbcencoder.Encoder
+	schema *Schema
+}
+
+// NewValidatingEncoder creates a new ValidatingEncoder that validates payloads against the given schema.
+// It compiles the schema, see Schema.Compile, and returns an error if a pattern is invalid.
+func NewValidatingEncoder(inner sbcencoder.Encoder, schema *Schema) (*ValidatingEncoder, error) {
+	if err := schema.Compile(); err != nil {
+		return nil, fmt.Errorf("failed to compile schema: %w", err)
+	}
+	return &ValidatingEncoder{inner: inner, schema: schema}, nil
+}
+
+// NewValidatingEncoderFor creates a new ValidatingEncoder with the schema generated from type T.
+// The generated schemas have no patterns, so there is nothing to compile.
+func NewValidatingEncoderFor[T any](inner sbcencoder.Encoder) *ValidatingEncoder {
+	return &ValidatingEncoder{inner: inner, schema: For[T]()}
+}
+
+// Encode encodes the given value v with the wrapped encoder.
+func (e *ValidatingEncoder) Encode(v any) ([]byte, error) {
+	return e.inner.Encode(v)
+}
+
+// Decode validates the byte slice against the schema and decodes it into v.
+func (e *ValidatingEncoder) Decode(data []byte, v any) error {
+	return e.DecodeWithHeaders(data, nil, v)
+}
+
+// DecodeWithHeaders validates the byte slice against the schema and decodes it into v, passing the headers
+// to the wrapped encoder if it is a sbcencoder.HeaderDecoder.
+func (e *ValidatingEncoder) DecodeWithHeaders(data []byte, headers map[string]string, v any) error {
+	decode := e.inner.Decode
+	if hd, ok := e.inner.(sbcencoder.HeaderDecoder); ok {
+		decode = func(data []byte, v any) error { return hd.DecodeWithHeaders(data, headers, v) }
+	}
+	var doc any
+	if err := decode(data, &doc); err != nil {
+		return err
+	}
+	if err := e.schema.ValidateValue(doc); err != nil {
+		return fmt.Errorf("failed to validate value: %w", err)
+	}
+	return decode(data, v)
+}
@@ -0,0 +1,124 @@
+package sbcschema_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+	"github.com/Autodoc-Technology/streaming-based-config/sbcencoder"
+	"github.com/Autodoc-Technology/streaming-based-config/sbcschema"
+)
+
+type validatingConfig struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+}
+
+func TestValidatingEncoderDecodeValid(t *testing.T) {
+	encoder := sbcschema.NewValidatingEncoderFor[validatingConfig](sbcencoder.NewJsonEncoder())
+	var v validatingConfig
+	err := encoder.Decode([]byte(`{"name": "app", "limit": 10}`), &v)
+	if err != nil || v.Name != "app" || v.Limit != 10 {
+		t.Errorf("unexpected result %+v, %v", v, err)
+	}
+}
+
+func TestValidatingEncoderDecodeInvalid(t *testing.T) {
+	encoder := sbcschema.NewValidatingEncoderFor[validatingConfig](sbcencoder.NewYamlEncoder())
+	v := validatingConfig{Name: "untouched"}
+	err := encoder.Decode([]byte("name: app\nlimit: 1.5\n"), &v)
+	var verr *sbcschema.ValidationError
+	if !errors.As(err, &verr) || verr.Errors[0].Pointer != "/limit" {
+		t.Errorf("Expected ValidationError for /limit, got %v", err)
+	}
+	if v.Name != "untouched" {
+		t.Error("value was decoded despite the validation error")
+	}
+}
+
+func TestValidatingEncoderEncode(t *testing.T) {
+	encoder := sbcschema.NewValidatingEncoderFor[validatingConfig](sbcencoder.NewJsonEncoder())
+	data, err := encoder.Encode(validatingConfig{Name: "app"})
+	if err != nil || string(data) != `{"name":"app","limit":0}` {
+		t.Errorf("unexpected result %q, %v", data, err)
+	}
+}
+
+// updatesTransport is a transport whose key has the initial value and receives the values sent to updates.
+type updatesTransport struct {
+	initial []byte
+	updates chan []byte
+}
+
+func (u updatesTransport) Current(context.Context, string) ([]byte, error) {
+	return u.initial, nil
+}
+
+func (u updatesTransport) Updates(context.Context, string) (<-chan []byte, error) {
+	return u.updates, nil
+}
+
+func TestValidatingEncoderSubscriptionError(t *testing.T) {
+	transport := updatesTransport{initial: []byte(`{"name": "app", "limit": 10}`), updates: make(chan []byte)}
+	encoder := sbcschema.NewValidatingEncoderFor[validatingConfig](sbcencoder.NewJsonEncoder())
+	sub := sbc.NewSubscriber[validatingConfig](transport,
+		sbc.KeyBuilderFunc[validatingConfig](func(validatingConfig) string { return "config" }),
+		sbc.WithEncoder(encoder), sbc.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	transport.updates <- []byte(`{"name": "app", "limit": "many"}`)
+	deadline := time.Now().Add(time.Second)
+	for confSubs.ApplyError() == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// the pointer of the invalid field is visible to the caller of the subscription
+	var verr *sbcschema.ValidationError
+	if err := confSubs.ApplyError(); !errors.As(err, &verr) || verr.Errors[0].Pointer != "/limit" {
+		t.Errorf("Expected ValidationError for /limit, got %v", err)
+	}
+	if confSubs.Get().Limit != 10 {
+		t.Errorf("unexpected value %+v", confSubs.Get())
+	}
+}
+
+func TestNewValidatingEncoderCompilesSchema(t *testing.T) {
+	schema := &sbcschema.Schema{Properties: map[string]*sbcschema.Schema{"name": {Pattern: "("}}}
+	if _, err := sbcschema.NewValidatingEncoder(sbcencoder.NewJsonEncoder(), schema); err == nil {
+		t.Error("Expected an invalid pattern error")
+	}
+	schema = &sbcschema.Schema{Properties: map[string]*sbcschema.Schema{"name": {Pattern: "^[a-z]+$"}}}
+	encoder, err := sbcschema.NewValidatingEncoder(sbcencoder.NewJsonEncoder(), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v validatingConfig
+	if err := encoder.Decode([]byte(`{"name": "app"}`), &v); err != nil {
+		t.Error(err)
+	}
+	var verr *sbcschema.ValidationError
+	if err := encoder.Decode([]byte(`{"name": "App"}`), &v); !errors.As(err, &verr) || verr.Errors[0].Pointer != "/name" {
+		t.Errorf("Expected ValidationError for /name, got %v", err)
+	}
+}
+
+func TestValidatingEncoderDecodeWithHeaders(t *testing.T) {
+	mux, _ := sbcencoder.NewMuxEncoder(sbcencoder.FormatJSON)
+	encoder := sbcschema.NewValidatingEncoderFor[validatingConfig](mux)
+	var v validatingConfig
+	err := encoder.DecodeWithHeaders([]byte("name: app\nlimit: 10\n"), map[string]string{sbcencoder.ContentTypeHeader: "yaml"}, &v)
+	if err != nil || v.Name != "app" || v.Limit != 10 {
+		t.Errorf("unexpected result %+v, %v", v, err)
+	}
+	// the header reaches the wrapped MuxEncoder and wins over the sniffed format
+	err = encoder.DecodeWithHeaders([]byte("name: app\n"), map[string]string{sbcencoder.ContentTypeHeader: "application/json"}, &v)
+	if err == nil {
+		t.Error("Expected JSON decoding error")
+	}
+}
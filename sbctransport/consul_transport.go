@@ -2,10 +2,14 @@ package sbctransport
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"sort"
+	"strconv"
 	"time"
 
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
 	capi "github.com/hashicorp/consul/api"
 )
 
@@ -14,8 +18,17 @@ type ConsulTransport struct {
 	kv             *capi.KV
 	updateInterval time.Duration
 	updateJitter   time.Duration
+	tracker        *tracker
 }
 
+// ensure ConsulTransport implements the optional transport interfaces
+var (
+	_ sbc.UpdateTransport = (*ConsulTransport)(nil)
+	_ sbc.PrefixTransport = (*ConsulTransport)(nil)
+	_ sbc.Lister          = (*ConsulTransport)(nil)
+	_ sbc.Closer          = (*ConsulTransport)(nil)
+)
+
 // defaultUpdateInterval is the default update interval for ConsulTransport.
 const defaultUpdateInterval = 10 * time.Second
 
@@ -24,7 +37,7 @@ const defaultUpdateJitter = 1 * time.Second
 
 // NewConsulTransport creates a new ConsulTransport.
 func NewConsulTransport(kv *capi.KV, opts ...ConsulTransportOpt) *ConsulTransport {
-	c := &ConsulTransport{kv: kv, updateInterval: defaultUpdateInterval, updateJitter: defaultUpdateJitter}
+	c := &ConsulTransport{kv: kv, updateInterval: defaultUpdateInterval, updateJitter: defaultUpdateJitter, tracker: newTracker()}
 	// Apply options
 	for _, opt := range opts {
 		opt(c)
@@ -32,8 +45,23 @@ func NewConsulTransport(kv *capi.KV, opts ...ConsulTransportOpt) *ConsulTranspor
 	return c
 }
 
+// Close stops the watches of the transport, cancels its running queries, and waits until their goroutines exit
+// or the context is done. The transport cannot be used afterwards. The Consul client belongs to the caller
+// and is not closed.
+func (c ConsulTransport) Close(ctx context.Context) error {
+	if err := c.tracker.close(ctx); err != nil {
+		return fmt.Errorf("failed to wait for the Consul KV watches: %w", err)
+	}
+	return nil
+}
+
 // Current retrieves the current value for the specified key from the Consul Key-Value store.
 func (c ConsulTransport) Current(ctx context.Context, key string) ([]byte, error) {
+	ctx, done, err := c.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 	pair, _, err := c.get(ctx, key)
 	if err != nil {
 		return nil, err
@@ -43,30 +71,244 @@ func (c ConsulTransport) Current(ctx context.Context, key string) ([]byte, error
 
 // Updates creates a channel that streams updates for a given key in the Consul KV store, emitting updated values.
 func (c ConsulTransport) Updates(ctx context.Context, key string) (<-chan []byte, error) {
+	ctx, done, err := c.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	updates, err := c.WatchUpdates(ctx, key)
+	if err != nil {
+		done()
+		return nil, err
+	}
 	ch := make(chan []byte)
 	go func() {
+		defer done()
+		defer close(ch)
+		for upd := range updates {
+			if upd.Operation != sbc.OpPut {
+				continue
+			}
+			select {
+			case ch <- upd.Value:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// CurrentUpdate retrieves the current value for the specified key with its metadata from the Consul KV store.
+func (c ConsulTransport) CurrentUpdate(ctx context.Context, key string) (sbc.Update, error) {
+	ctx, done, err := c.tracker.begin(ctx)
+	if err != nil {
+		return sbc.Update{}, err
+	}
+	defer done()
+	pair, _, err := c.get(ctx, key)
+	if err != nil {
+		return sbc.Update{}, err
+	}
+	return consulUpdate(pair), nil
+}
+
+// WatchUpdates creates a channel that streams the updates, deletes included, for a given key in the Consul KV store.
+// The key is polled every update interval, a delete is emitted when a key that was seen before disappears.
+func (c ConsulTransport) WatchUpdates(ctx context.Context, key string) (<-chan sbc.Update, error) {
+	ctx, done, err := c.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan sbc.Update)
+	go func() {
+		defer done()
 		defer close(ch)
 		lastIndex := uint64(0)
+		seen := false
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-time.After(c.getIntervalWithJitter()):
 				pair, meta, err := c.get(ctx, key)
-				if err != nil {
+				if err != nil && !errors.Is(err, ErrEmptyKey) {
 					continue
 				}
 				if meta.LastIndex <= lastIndex {
 					continue
 				}
 				lastIndex = meta.LastIndex
-				ch <- pair.Value
+				upd := sbc.Update{Key: key, Timestamp: time.Now(), Operation: sbc.OpDelete}
+				if pair != nil {
+					upd = consulUpdate(pair)
+				} else if !seen {
+					continue
+				}
+				seen = pair != nil
+				select {
+				case ch <- upd:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}()
 	return ch, nil
 }
 
+// consulUpdate converts a Consul KV pair into an update.
+// Consul does not track modification times, so the update is timestamped with the time of receipt.
+func consulUpdate(pair *capi.KVPair) sbc.Update {
+	headers := map[string]string{FlagsHeader: strconv.FormatUint(pair.Flags, 10)}
+	if pair.Session != "" {
+		headers[SessionHeader] = pair.Session
+	}
+	return sbc.Update{
+		Key:       pair.Key,
+		Value:     pair.Value,
+		Revision:  pair.ModifyIndex,
+		Timestamp: time.Now(),
+		Operation: sbc.OpPut,
+		Headers:   headers,
+	}
+}
+
+// defaultBlockingWaitTime is the maximum duration of a Consul blocking query.
+const defaultBlockingWaitTime = 5 * time.Minute
+
+// CurrentPrefix retrieves the current values of all keys starting with the prefix with their metadata
+// from the Consul Key-Value store.
+func (c ConsulTransport) CurrentPrefix(ctx context.Context, prefix string) (map[string]sbc.Update, error) {
+	ctx, done, err := c.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	pairs, _, err := c.list(ctx, prefix, 0)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]sbc.Update, len(pairs))
+	for _, pair := range pairs {
+		values[pair.Key] = consulUpdate(pair)
+	}
+	return values, nil
+}
+
+// UpdatesPrefix creates a channel that streams the changes of all keys starting with the prefix in the Consul KV store.
+// It uses blocking queries on the recursive KV listing, so changes are received as soon as they happen.
+// The changes are diffed against a listing taken before it returns, so all the changes made afterwards are streamed.
+func (c ConsulTransport) UpdatesPrefix(ctx context.Context, prefix string) (<-chan sbc.Update, error) {
+	ctx, done, err := c.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pairs, meta, err := c.list(ctx, prefix, 0)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	ch := make(chan sbc.Update)
+	go func() {
+		defer done()
+		defer close(ch)
+		known := indexPairs(pairs)
+		lastIndex := meta.LastIndex
+		for {
+			pairs, meta, err := c.list(ctx, prefix, lastIndex)
+			if err != nil {
+				// back off before retrying
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(c.getIntervalWithJitter()):
+					continue
+				}
+			}
+			// the index can go backwards, e.g. after a snapshot restore, start over in that case
+			if meta.LastIndex < lastIndex {
+				lastIndex = 0
+			} else {
+				lastIndex = meta.LastIndex
+			}
+			current := indexPairs(pairs)
+			for _, upd := range diffPairs(known, current) {
+				select {
+				case ch <- upd:
+				case <-ctx.Done():
+					return
+				}
+			}
+			known = current
+		}
+	}()
+	return ch, nil
+}
+
+// List returns the keys starting with the prefix and their revisions (ModifyIndex) from the Consul KV store.
+// KV.Keys does not return the modify indexes, so the keys are read with a recursive KV.List.
+func (c ConsulTransport) List(ctx context.Context, prefix string) ([]sbc.KeyInfo, error) {
+	ctx, done, err := c.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	pairs, _, err := c.list(ctx, prefix, 0)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]sbc.KeyInfo, 0, len(pairs))
+	for _, pair := range pairs {
+		keys = append(keys, sbc.KeyInfo{Key: pair.Key, Revision: pair.ModifyIndex})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Key < keys[j].Key
+	})
+	return keys, nil
+}
+
+// list lists all pairs starting with the prefix, blocking until the index is greater than waitIndex.
+func (c ConsulTransport) list(ctx context.Context, prefix string, waitIndex uint64) (capi.KVPairs, *capi.QueryMeta, error) {
+	opts := &capi.QueryOptions{
+		RequireConsistent: true,
+		WaitIndex:         waitIndex,
+		WaitTime:          defaultBlockingWaitTime,
+	}
+	pairs, meta, err := c.kv.List(prefix, opts.WithContext(ctx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list prefix '%s' from Consul KV: %w", prefix, err)
+	}
+	return pairs, meta, nil
+}
+
+// indexPairs indexes the pairs by key.
+func indexPairs(pairs capi.KVPairs) map[string]*capi.KVPair {
+	m := make(map[string]*capi.KVPair, len(pairs))
+	for _, pair := range pairs {
+		m[pair.Key] = pair
+	}
+	return m
+}
+
+// diffPairs returns the updates that turn the known pairs into the current ones, ordered by key.
+func diffPairs(known, current map[string]*capi.KVPair) []sbc.Update {
+	var updates []sbc.Update
+	for key, pair := range current {
+		if prev, ok := known[key]; !ok || prev.ModifyIndex != pair.ModifyIndex {
+			updates = append(updates, consulUpdate(pair))
+		}
+	}
+	for key := range known {
+		if _, ok := current[key]; !ok {
+			updates = append(updates, sbc.Update{Key: key, Timestamp: time.Now(), Operation: sbc.OpDelete})
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].Key < updates[j].Key
+	})
+	return updates
+}
+
 // ErrEmptyKey is an error that is returned when the key is empty.
 var ErrEmptyKey = fmt.Errorf("key is empty")
 
@@ -0,0 +1,218 @@
+package sbctransport_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+	"github.com/Autodoc-Technology/streaming-based-config/sbctransport"
+	capi "github.com/hashicorp/consul/api"
+)
+
+// fakeConsul is an in-memory Consul KV HTTP API, the blocking queries wait until the index changes.
+type fakeConsul struct {
+	mu      sync.Mutex
+	index   uint64
+	pairs   map[string]*capi.KVPair
+	changed chan struct{}
+}
+
+func newFakeConsul(t *testing.T) (*fakeConsul, *capi.KV) {
+	fc := &fakeConsul{pairs: map[string]*capi.KVPair{}, changed: make(chan struct{})}
+	srv := httptest.NewServer(fc)
+	t.Cleanup(srv.Close)
+	client, err := capi.NewClient(&capi.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fc, client.KV()
+}
+
+func (fc *fakeConsul) put(key, value string) {
+	fc.putPair(&capi.KVPair{Key: key, Value: []byte(value)})
+}
+
+// putPair stores the pair with the next modify index.
+func (fc *fakeConsul) putPair(pair *capi.KVPair) {
+	fc.change(func() {
+		pair.ModifyIndex = fc.index
+		fc.pairs[pair.Key] = pair
+	})
+}
+
+func (fc *fakeConsul) delete(key string) {
+	fc.change(func() { delete(fc.pairs, key) })
+}
+
+// change applies the change with the next index and wakes up the blocking queries.
+func (fc *fakeConsul) change(apply func()) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.index++
+	apply()
+	close(fc.changed)
+	fc.changed = make(chan struct{})
+}
+
+func (fc *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Path[len("/v1/kv/"):]
+	waitIndex, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	for {
+		fc.mu.Lock()
+		index, changed := fc.index, fc.changed
+		var pairs capi.KVPairs
+		for k, pair := range fc.pairs {
+			if k == key || r.URL.Query().Has("recurse") && len(k) >= len(key) && k[:len(key)] == key {
+				pairs = append(pairs, pair)
+			}
+		}
+		fc.mu.Unlock()
+		if waitIndex == 0 || index > waitIndex {
+			w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+			if len(pairs) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(pairs)
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func TestConsulTransportClose(t *testing.T) {
+	fc, kv := newFakeConsul(t)
+	fc.put("tenants/1", "1")
+	transport := sbctransport.NewConsulTransport(kv, sbctransport.WithUpdateInterval(100*time.Millisecond))
+	ctx := context.Background()
+	updates, err := transport.UpdatesPrefix(ctx, "tenants/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := transport.Updates(ctx, "tenants/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.put("tenants/2", "2")
+	if upd := <-updates; upd.Key != "tenants/2" {
+		t.Errorf("Expected 'tenants/2', got '%s'", upd.Key)
+	}
+	// the prefix watch is blocked in a Consul query, Close cancels it
+	if err := transport.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for range values {
+	}
+	for range updates {
+	}
+	checkNoTransportGoroutines(t)
+	if _, err := transport.CurrentUpdate(ctx, "tenants/1"); !errors.Is(err, sbctransport.ErrTransportClosed) {
+		t.Errorf("Expected ErrTransportClosed, got %v", err)
+	}
+}
+
+func TestConsulTransportWatchUpdates(t *testing.T) {
+	fc, kv := newFakeConsul(t)
+	fc.put("other", "1")
+	transport := sbctransport.NewConsulTransport(kv, sbctransport.WithUpdateInterval(100*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := transport.WatchUpdates(ctx, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the key was never seen, so nothing is emitted for its absence
+	select {
+	case upd := <-updates:
+		t.Fatalf("unexpected update %+v", upd)
+	case <-time.After(250 * time.Millisecond):
+	}
+	fc.putPair(&capi.KVPair{Key: "config", Value: []byte("1"), Flags: 42, Session: "lock"})
+	upd := <-updates
+	if upd.Key != "config" || string(upd.Value) != "1" || upd.Operation != sbc.OpPut || upd.Revision != 2 || upd.Timestamp.IsZero() {
+		t.Errorf("unexpected update %+v", upd)
+	}
+	if upd.Headers[sbctransport.FlagsHeader] != "42" || upd.Headers[sbctransport.SessionHeader] != "lock" {
+		t.Errorf("unexpected headers %v", upd.Headers)
+	}
+	fc.delete("config")
+	if upd := <-updates; upd.Key != "config" || upd.Operation != sbc.OpDelete || upd.Value != nil {
+		t.Errorf("unexpected update %+v", upd)
+	}
+}
+
+func TestConsulTransportUpdatesPrefix(t *testing.T) {
+	fc, kv := newFakeConsul(t)
+	fc.put("tenants/1", "1")
+	transport := sbctransport.NewConsulTransport(kv)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := transport.UpdatesPrefix(ctx, "tenants/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		change   func()
+		key      string
+		value    string
+		op       sbc.Operation
+		revision uint64
+	}{
+		{name: "create", change: func() { fc.put("tenants/2", "2") }, key: "tenants/2", value: "2", op: sbc.OpPut, revision: 2},
+		{name: "update", change: func() { fc.put("tenants/1", "3") }, key: "tenants/1", value: "3", op: sbc.OpPut, revision: 3},
+		{name: "other prefix", change: func() { fc.put("other/1", "4") }},
+		{name: "delete", change: func() { fc.delete("tenants/2") }, key: "tenants/2", op: sbc.OpDelete},
+	}
+	for _, tt := range tests {
+		tt.change()
+		if tt.key == "" {
+			continue
+		}
+		upd := <-updates
+		if upd.Key != tt.key || string(upd.Value) != tt.value || upd.Operation != tt.op || upd.Revision != tt.revision {
+			t.Errorf("%s: unexpected update %+v", tt.name, upd)
+		}
+		if tt.op == sbc.OpPut && upd.Headers[sbctransport.FlagsHeader] != "0" {
+			t.Errorf("%s: unexpected headers %v", tt.name, upd.Headers)
+		}
+	}
+}
+
+func TestConsulTransportList(t *testing.T) {
+	fc, kv := newFakeConsul(t)
+	fc.put("tenants/2", "2")
+	fc.put("tenants/1", "1")
+	fc.put("other", "3")
+	fc.put("tenants/3", "4")
+	fc.delete("tenants/3")
+	fc.put("tenants/2", "5")
+	transport := sbctransport.NewConsulTransport(kv)
+	tests := []struct {
+		prefix string
+		keys   []sbc.KeyInfo
+	}{
+		{prefix: "tenants/", keys: []sbc.KeyInfo{{Key: "tenants/1", Revision: 2}, {Key: "tenants/2", Revision: 6}}},
+		{prefix: "ten", keys: []sbc.KeyInfo{{Key: "tenants/1", Revision: 2}, {Key: "tenants/2", Revision: 6}}},
+		{prefix: "", keys: []sbc.KeyInfo{{Key: "other", Revision: 3}, {Key: "tenants/1", Revision: 2}, {Key: "tenants/2", Revision: 6}}},
+		{prefix: "missing/", keys: []sbc.KeyInfo{}},
+	}
+	for _, tt := range tests {
+		keys, err := transport.List(context.Background(), tt.prefix)
+		if err != nil || !slices.Equal(keys, tt.keys) {
+			t.Errorf("prefix '%s': Expected %v, got %v, %v", tt.prefix, tt.keys, keys, err)
+		}
+	}
+}
@@ -31,7 +31,12 @@ func (d DemoTransport) Updates(ctx context.Context, key string) (<-chan []byte,
 				close(ch)
 				return
 			case <-ticker.C:
-				ch <- d.payload
+				select {
+				case ch <- d.payload:
+				case <-ctx.Done():
+					close(ch)
+					return
+				}
 			}
 		}
 	}()
@@ -2,16 +2,25 @@ package sbctransport
 
 import (
 	"context"
+	"sort"
+	"strings"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
 )
 
 // FixedTransport is transport that returns a predefined payload for data retrieval operations.
 type FixedTransport struct {
 	payload []byte
+	keys    []string
 }
 
+// ensure FixedTransport implements sbc.Lister
+var _ sbc.Lister = (*FixedTransport)(nil)
+
 // NewFixedTransport creates a FixedTransport instance with a predefined payload for retrieval operations.
-func NewFixedTransport(payload []byte) *FixedTransport {
-	return &FixedTransport{payload: payload}
+// The optional keys are reported by List, Current returns the payload for any key.
+func NewFixedTransport(payload []byte, keys ...string) *FixedTransport {
+	return &FixedTransport{payload: payload, keys: keys}
 }
 
 // Current retrieves the predefined payload associated with the FixedTransport instance.
@@ -28,3 +37,17 @@ func (d FixedTransport) Updates(ctx context.Context, _ string) (<-chan []byte, e
 	}()
 	return ch, nil
 }
+
+// List returns the predefined keys starting with the prefix, the payload never changes, so the revision is zero.
+func (d FixedTransport) List(_ context.Context, prefix string) ([]sbc.KeyInfo, error) {
+	var keys []sbc.KeyInfo
+	for _, key := range d.keys {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, sbc.KeyInfo{Key: key})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Key < keys[j].Key
+	})
+	return keys, nil
+}
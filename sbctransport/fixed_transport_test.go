@@ -0,0 +1,35 @@
+package sbctransport_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+	"github.com/Autodoc-Technology/streaming-based-config/sbctransport"
+)
+
+func TestFixedTransportList(t *testing.T) {
+	transport := sbctransport.NewFixedTransport([]byte("{}"), "tenants/2", "other", "tenants/1")
+	tests := []struct {
+		prefix string
+		keys   []sbc.KeyInfo
+	}{
+		{prefix: "tenants/", keys: []sbc.KeyInfo{{Key: "tenants/1"}, {Key: "tenants/2"}}},
+		{prefix: "", keys: []sbc.KeyInfo{{Key: "other"}, {Key: "tenants/1"}, {Key: "tenants/2"}}},
+		{prefix: "missing/", keys: nil},
+	}
+	for _, tt := range tests {
+		keys, err := transport.List(context.Background(), tt.prefix)
+		if err != nil || !slices.Equal(keys, tt.keys) {
+			t.Errorf("prefix '%s': Expected %v, got %v, %v", tt.prefix, tt.keys, keys, err)
+		}
+	}
+}
+
+func TestNothingTransportList(t *testing.T) {
+	keys, err := sbctransport.NewNothingTransport().List(context.Background(), "")
+	if err != nil || len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v, %v", keys, err)
+	}
+}
@@ -0,0 +1,17 @@
+package sbctransport
+
+import (
+	"github.com/Autodoc-Technology/streaming-based-config/sbcencoder"
+)
+
+const (
+	// BucketHeader is the update header that carries the name of the NATS KV bucket.
+	BucketHeader = "Bucket"
+
+	// FlagsHeader is the update header that carries the Consul KVPair.Flags value.
+	// It is understood by sbcencoder.MuxEncoder to pick the payload format.
+	FlagsHeader = sbcencoder.FlagsHeader
+
+	// SessionHeader is the update header that carries the Consul session holding the lock on the key, if any.
+	SessionHeader = "Session"
+)
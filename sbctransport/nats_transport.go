@@ -3,21 +3,48 @@ package sbctransport
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
 	"github.com/nats-io/nats.go/jetstream"
 )
 
 // NatsTransport represents a transport mechanism for accessing and manipulating data stored in NATS Key-Value store.
 type NatsTransport struct {
-	kv jetstream.KeyValue
+	kv      jetstream.KeyValue
+	tracker *tracker
 }
 
+// ensure NatsTransport implements the optional transport interfaces
+var (
+	_ sbc.UpdateTransport = (*NatsTransport)(nil)
+	_ sbc.PrefixTransport = (*NatsTransport)(nil)
+	_ sbc.Lister          = (*NatsTransport)(nil)
+	_ sbc.Closer          = (*NatsTransport)(nil)
+)
+
 // NewNatsTransport creates a new NatsTransport.
 func NewNatsTransport(kv jetstream.KeyValue) *NatsTransport {
-	return &NatsTransport{kv: kv}
+	return &NatsTransport{kv: kv, tracker: newTracker()}
+}
+
+// Close stops the watches of the transport, cancels its running calls, and waits until their goroutines exit
+// or the context is done. The transport cannot be used afterwards. The NATS connection belongs to the caller
+// and is not closed.
+func (n NatsTransport) Close(ctx context.Context) error {
+	if err := n.tracker.close(ctx); err != nil {
+		return fmt.Errorf("failed to wait for the NATS KV watches: %w", err)
+	}
+	return nil
 }
 
 func (n NatsTransport) Current(ctx context.Context, key string) ([]byte, error) {
+	ctx, done, err := n.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 	entry, err := n.kv.Get(ctx, key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current value for key '%s' from NATS KV: %w", key, err)
@@ -26,16 +53,218 @@ func (n NatsTransport) Current(ctx context.Context, key string) ([]byte, error)
 }
 
 func (n NatsTransport) Updates(ctx context.Context, key string) (<-chan []byte, error) {
+	ctx, done, err := n.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
 	watcher, err := n.kv.Watch(ctx, key, jetstream.UpdatesOnly(), jetstream.IgnoreDeletes())
 	if err != nil {
+		done()
 		return nil, fmt.Errorf("failed to watch updates from NATS KV: %w", err)
 	}
 	ch := make(chan []byte)
 	go func() {
+		defer done()
+		defer close(ch)
+		defer func() { _ = watcher.Stop() }()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case entry, ok := <-watcher.Updates():
+				if !ok {
+					return
+				}
+				if entry == nil {
+					continue
+				}
+				select {
+				case ch <- entry.Value():
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// CurrentUpdate retrieves the current value for the specified key with its metadata from the NATS KV store.
+func (n NatsTransport) CurrentUpdate(ctx context.Context, key string) (sbc.Update, error) {
+	ctx, done, err := n.tracker.begin(ctx)
+	if err != nil {
+		return sbc.Update{}, err
+	}
+	defer done()
+	entry, err := n.kv.Get(ctx, key)
+	if err != nil {
+		return sbc.Update{}, fmt.Errorf("failed to get current value for key '%s' from NATS KV: %w", key, err)
+	}
+	return natsUpdate(entry), nil
+}
+
+// WatchUpdates creates a channel that streams the updates, deletes included, for a given key in the NATS KV store.
+func (n NatsTransport) WatchUpdates(ctx context.Context, key string) (<-chan sbc.Update, error) {
+	ctx, done, err := n.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := n.kv.Watch(ctx, key, jetstream.UpdatesOnly())
+	if err != nil {
+		done()
+		return nil, fmt.Errorf("failed to watch updates from NATS KV: %w", err)
+	}
+	ch := make(chan sbc.Update)
+	go func() {
+		defer done()
+		defer close(ch)
+		defer func() { _ = watcher.Stop() }()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case entry, ok := <-watcher.Updates():
+				if !ok {
+					return
+				}
+				if entry == nil {
+					continue
+				}
+				select {
+				case ch <- natsUpdate(entry):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// CurrentPrefix retrieves the current values of all keys starting with the prefix with their metadata
+// from the NATS KV store.
+func (n NatsTransport) CurrentPrefix(ctx context.Context, prefix string) (map[string]sbc.Update, error) {
+	ctx, done, err := n.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	watcher, err := n.kv.Watch(ctx, natsWatchPattern(prefix), jetstream.IgnoreDeletes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch prefix '%s' from NATS KV: %w", prefix, err)
+	}
+	defer func() { _ = watcher.Stop() }()
+	values := make(map[string]sbc.Update)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case entry, ok := <-watcher.Updates():
+			// a nil entry marks the end of the initial values
+			if !ok || entry == nil {
+				return values, nil
+			}
+			if strings.HasPrefix(entry.Key(), prefix) {
+				values[entry.Key()] = natsUpdate(entry)
+			}
+		}
+	}
+}
+
+// UpdatesPrefix creates a channel that streams the changes of all keys starting with the prefix in the NATS KV store.
+func (n NatsTransport) UpdatesPrefix(ctx context.Context, prefix string) (<-chan sbc.Update, error) {
+	ctx, done, err := n.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := n.kv.Watch(ctx, natsWatchPattern(prefix), jetstream.UpdatesOnly())
+	if err != nil {
+		done()
+		return nil, fmt.Errorf("failed to watch prefix '%s' from NATS KV: %w", prefix, err)
+	}
+	ch := make(chan sbc.Update)
+	go func() {
+		defer done()
 		defer close(ch)
-		for entry := range watcher.Updates() {
-			ch <- entry.Value()
+		defer func() { _ = watcher.Stop() }()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case entry, ok := <-watcher.Updates():
+				if !ok {
+					return
+				}
+				if entry == nil || !strings.HasPrefix(entry.Key(), prefix) {
+					continue
+				}
+				select {
+				case ch <- natsUpdate(entry):
+				case <-ctx.Done():
+					return
+				}
+			}
 		}
 	}()
 	return ch, nil
 }
+
+// List returns the keys starting with the prefix and their revisions from the NATS KV store.
+// It reads the keys with the same metadata-only watch kv.ListKeys uses, which also yields the revisions.
+func (n NatsTransport) List(ctx context.Context, prefix string) ([]sbc.KeyInfo, error) {
+	ctx, done, err := n.tracker.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	watcher, err := n.kv.Watch(ctx, natsWatchPattern(prefix), jetstream.IgnoreDeletes(), jetstream.MetaOnly())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys with prefix '%s' from NATS KV: %w", prefix, err)
+	}
+	defer func() { _ = watcher.Stop() }()
+	var keys []sbc.KeyInfo
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case entry, ok := <-watcher.Updates():
+			// a nil entry marks the end of the existing keys
+			if !ok || entry == nil {
+				sort.Slice(keys, func(i, j int) bool {
+					return keys[i].Key < keys[j].Key
+				})
+				return keys, nil
+			}
+			if strings.HasPrefix(entry.Key(), prefix) {
+				keys = append(keys, sbc.KeyInfo{Key: entry.Key(), Revision: entry.Revision()})
+			}
+		}
+	}
+}
+
+// natsUpdate converts a NATS KV entry into an update, purges are reported as deletes.
+func natsUpdate(entry jetstream.KeyValueEntry) sbc.Update {
+	op := sbc.OpPut
+	if entry.Operation() != jetstream.KeyValuePut {
+		op = sbc.OpDelete
+	}
+	return sbc.Update{
+		Key:       entry.Key(),
+		Value:     entry.Value(),
+		Revision:  entry.Revision(),
+		Timestamp: entry.Created(),
+		Operation: op,
+		Headers:   map[string]string{BucketHeader: entry.Bucket()},
+	}
+}
+
+// natsWatchPattern returns the subject pattern of the server-side filter for the keys starting with the prefix.
+// NATS wildcards match whole dot-separated tokens only, so the server filters by the complete tokens
+// of the prefix, and the rest of the prefix is filtered by the transport: "app.tenants." watches "app.tenants.>",
+// "app.ten" watches "app.>", and a prefix without a dot, like "tenants/", watches all keys of the bucket.
+func natsWatchPattern(prefix string) string {
+	if i := strings.LastIndex(prefix, "."); i >= 0 {
+		return prefix[:i+1] + ">"
+	}
+	return ">"
+}
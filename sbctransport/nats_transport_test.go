@@ -0,0 +1,290 @@
+package sbctransport_test
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
+	"github.com/Autodoc-Technology/streaming-based-config/sbctransport"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// fakeKV is an in-memory jetstream.KeyValue with the methods used by NatsTransport. The watches receive
+// the changes of the keys matching their pattern, preceded by the current values and a nil entry
+// unless they are updates-only.
+type fakeKV struct {
+	jetstream.KeyValue
+	mu       sync.Mutex
+	revision uint64
+	entries  map[string]fakeEntry
+	watchers []*fakeWatcher
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{entries: map[string]fakeEntry{}}
+}
+
+func (kv *fakeKV) Get(_ context.Context, key string) (jetstream.KeyValueEntry, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	entry, ok := kv.entries[key]
+	if !ok || entry.op != jetstream.KeyValuePut {
+		return nil, jetstream.ErrKeyNotFound
+	}
+	return entry, nil
+}
+
+func (kv *fakeKV) Watch(_ context.Context, pattern string, opts ...jetstream.WatchOpt) (jetstream.KeyWatcher, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	w := &fakeWatcher{pattern: pattern, updates: make(chan jetstream.KeyValueEntry, 64)}
+	if !hasWatchOpt(opts, jetstream.UpdatesOnly()) {
+		keys := slices.Sorted(maps.Keys(kv.entries))
+		for _, key := range keys {
+			entry := kv.entries[key]
+			if matchSubject(pattern, key) && (entry.op == jetstream.KeyValuePut || !hasWatchOpt(opts, jetstream.IgnoreDeletes())) {
+				w.updates <- entry
+			}
+		}
+		w.updates <- nil
+	}
+	kv.watchers = append(kv.watchers, w)
+	return w, nil
+}
+
+func (kv *fakeKV) put(key, value string) {
+	kv.change(key, []byte(value), jetstream.KeyValuePut)
+}
+
+func (kv *fakeKV) delete(key string) {
+	kv.change(key, nil, jetstream.KeyValueDelete)
+}
+
+func (kv *fakeKV) purge(key string) {
+	kv.change(key, nil, jetstream.KeyValuePurge)
+}
+
+// change records the change of the key and sends it to the matching watchers.
+func (kv *fakeKV) change(key string, value []byte, op jetstream.KeyValueOp) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.revision++
+	entry := fakeEntry{key: key, value: value, revision: kv.revision, op: op, created: time.Unix(int64(kv.revision), 0)}
+	kv.entries[key] = entry
+	for _, w := range kv.watchers {
+		if matchSubject(w.pattern, key) {
+			w.updates <- entry
+		}
+	}
+}
+
+// hasWatchOpt reports whether the options contain the option, the options are compared by their function.
+func hasWatchOpt(opts []jetstream.WatchOpt, opt jetstream.WatchOpt) bool {
+	for _, o := range opts {
+		if reflect.ValueOf(o).Pointer() == reflect.ValueOf(opt).Pointer() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSubject reports whether the key matches the NATS subject pattern with the * and > wildcards.
+func matchSubject(pattern, key string) bool {
+	patternTokens, keyTokens := strings.Split(pattern, "."), strings.Split(key, ".")
+	for i, token := range patternTokens {
+		switch {
+		case token == ">":
+			return len(keyTokens) > i
+		case i >= len(keyTokens):
+			return false
+		case token != "*" && token != keyTokens[i]:
+			return false
+		}
+	}
+	return len(patternTokens) == len(keyTokens)
+}
+
+// fakeWatcher is a jetstream.KeyWatcher of the fakeKV.
+type fakeWatcher struct {
+	pattern string
+	updates chan jetstream.KeyValueEntry
+	stopped bool
+}
+
+func (w *fakeWatcher) Updates() <-chan jetstream.KeyValueEntry { return w.updates }
+
+func (w *fakeWatcher) Stop() error {
+	w.stopped = true
+	return nil
+}
+
+// fakeEntry is a jetstream.KeyValueEntry of the fakeKV.
+type fakeEntry struct {
+	key      string
+	value    []byte
+	revision uint64
+	op       jetstream.KeyValueOp
+	created  time.Time
+}
+
+func (e fakeEntry) Bucket() string                  { return "configs" }
+func (e fakeEntry) Key() string                     { return e.key }
+func (e fakeEntry) Value() []byte                   { return e.value }
+func (e fakeEntry) Revision() uint64                { return e.revision }
+func (e fakeEntry) Created() time.Time              { return e.created }
+func (e fakeEntry) Delta() uint64                   { return 0 }
+func (e fakeEntry) Operation() jetstream.KeyValueOp { return e.op }
+
+func TestNatsTransportClose(t *testing.T) {
+	kv := newFakeKV()
+	kv.put("config", "1")
+	transport := sbctransport.NewNatsTransport(kv)
+	ctx := context.Background()
+	updates, err := transport.WatchUpdates(ctx, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := transport.Updates(ctx, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv.put("config", "2")
+	if upd := <-updates; string(upd.Value) != "2" {
+		t.Errorf("Expected '2', got '%s'", upd.Value)
+	}
+	if err := transport.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for range values {
+	}
+	for range updates {
+	}
+	for _, w := range kv.watchers {
+		if !w.stopped {
+			t.Errorf("Expected the watcher of '%s' to be stopped", w.pattern)
+		}
+	}
+	checkNoTransportGoroutines(t)
+	if _, err := transport.Current(ctx, "config"); !errors.Is(err, sbctransport.ErrTransportClosed) {
+		t.Errorf("Expected ErrTransportClosed, got %v", err)
+	}
+	if _, err := transport.UpdatesPrefix(ctx, "con"); !errors.Is(err, sbctransport.ErrTransportClosed) {
+		t.Errorf("Expected ErrTransportClosed, got %v", err)
+	}
+}
+
+func TestNatsTransportUpdatesPrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		pattern string
+	}{
+		{prefix: "app.tenants.", pattern: "app.tenants.>"},
+		{prefix: "app.ten", pattern: "app.>"},
+		{prefix: "tenants/", pattern: ">"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			kv := newFakeKV()
+			transport := sbctransport.NewNatsTransport(kv)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			updates, err := transport.UpdatesPrefix(ctx, tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kv.watchers[0].pattern != tt.pattern {
+				t.Errorf("Expected pattern '%s', got '%s'", tt.pattern, kv.watchers[0].pattern)
+			}
+			// the keys the server-side filter lets through are filtered by the prefix
+			kv.put("app.other", "1")
+			kv.put(tt.prefix+"1", "2")
+			if upd := <-updates; upd.Key != tt.prefix+"1" {
+				t.Errorf("Expected '%s1', got '%s'", tt.prefix, upd.Key)
+			}
+		})
+	}
+}
+
+func TestNatsTransportWatchUpdates(t *testing.T) {
+	kv := newFakeKV()
+	kv.put("config", "1")
+	transport := sbctransport.NewNatsTransport(kv)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := transport.WatchUpdates(ctx, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		change func()
+		value  string
+		op     sbc.Operation
+	}{
+		{name: "put", change: func() { kv.put("config", "2") }, value: "2", op: sbc.OpPut},
+		{name: "delete", change: func() { kv.delete("config") }, op: sbc.OpDelete},
+		{name: "put after delete", change: func() { kv.put("config", "3") }, value: "3", op: sbc.OpPut},
+		{name: "purge", change: func() { kv.purge("config") }, op: sbc.OpDelete},
+	}
+	for i, tt := range tests {
+		tt.change()
+		upd := <-updates
+		revision := uint64(i + 2)
+		if upd.Key != "config" || string(upd.Value) != tt.value || upd.Operation != tt.op {
+			t.Errorf("%s: unexpected update %+v", tt.name, upd)
+		}
+		if upd.Revision != revision || !upd.Timestamp.Equal(time.Unix(int64(revision), 0)) {
+			t.Errorf("%s: Expected revision %d, got %d at %v", tt.name, revision, upd.Revision, upd.Timestamp)
+		}
+		if upd.Headers[sbctransport.BucketHeader] != "configs" {
+			t.Errorf("%s: unexpected headers %v", tt.name, upd.Headers)
+		}
+	}
+}
+
+func TestNatsTransportCurrentUpdate(t *testing.T) {
+	kv := newFakeKV()
+	kv.put("config", "1")
+	transport := sbctransport.NewNatsTransport(kv)
+	upd, err := transport.CurrentUpdate(context.Background(), "config")
+	if err != nil || string(upd.Value) != "1" || upd.Revision != 1 || upd.Operation != sbc.OpPut || upd.Headers[sbctransport.BucketHeader] != "configs" {
+		t.Errorf("unexpected update %+v, %v", upd, err)
+	}
+	kv.delete("config")
+	if _, err := transport.CurrentUpdate(context.Background(), "config"); !errors.Is(err, jetstream.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestNatsTransportList(t *testing.T) {
+	kv := newFakeKV()
+	kv.put("app.tenants.2", "2")
+	kv.put("app.tenants.1", "1")
+	kv.put("app.other", "3")
+	kv.put("app.tenants.3", "4")
+	kv.delete("app.tenants.3")
+	kv.put("app.tenants.2", "5")
+	transport := sbctransport.NewNatsTransport(kv)
+	tests := []struct {
+		prefix string
+		keys   []sbc.KeyInfo
+	}{
+		{prefix: "app.tenants.", keys: []sbc.KeyInfo{{Key: "app.tenants.1", Revision: 2}, {Key: "app.tenants.2", Revision: 6}}},
+		{prefix: "app.ten", keys: []sbc.KeyInfo{{Key: "app.tenants.1", Revision: 2}, {Key: "app.tenants.2", Revision: 6}}},
+		{prefix: "", keys: []sbc.KeyInfo{{Key: "app.other", Revision: 3}, {Key: "app.tenants.1", Revision: 2}, {Key: "app.tenants.2", Revision: 6}}},
+		{prefix: "missing.", keys: nil},
+	}
+	for _, tt := range tests {
+		keys, err := transport.List(context.Background(), tt.prefix)
+		if err != nil || !slices.Equal(keys, tt.keys) {
+			t.Errorf("prefix '%s': Expected %v, got %v, %v", tt.prefix, tt.keys, keys, err)
+		}
+	}
+}
@@ -2,13 +2,18 @@ package sbctransport
 
 import (
 	"context"
+
+	sbc "github.com/Autodoc-Technology/streaming-based-config"
 )
 
 // NothingTransport represents a type that doesn't actually perform any transportation.
-// It is a struct with no fields and implements the Current and Updates methods
-// of the transport interface. Both methods return empty results or nil.
+// It is a struct with no fields and implements the Current, Updates and List methods
+// of the transport interfaces. All methods return empty results or nil.
 type NothingTransport struct{}
 
+// ensure NothingTransport implements sbc.Lister
+var _ sbc.Lister = (*NothingTransport)(nil)
+
 // NewNothingTransport creates a new NothingTransport.
 func NewNothingTransport() *NothingTransport {
 	return &NothingTransport{}
@@ -21,3 +26,8 @@ func (n NothingTransport) Current(_ context.Context, _ string) ([]byte, error) {
 func (n NothingTransport) Updates(_ context.Context, _ string) (<-chan []byte, error) {
 	return nil, nil
 }
+
+// List returns no keys.
+func (n NothingTransport) List(_ context.Context, _ string) ([]sbc.KeyInfo, error) {
+	return nil, nil
+}
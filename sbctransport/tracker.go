@@ -0,0 +1,65 @@
+package sbctransport
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTransportClosed is an error that is returned when a closed transport is used.
+var ErrTransportClosed = errors.New("transport is closed")
+
+// tracker tracks the calls and the watch goroutines of a transport, so Close can stop them
+// and wait until they exit.
+type tracker struct {
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newTracker creates a new tracker.
+func newTracker() *tracker {
+	t := &tracker{}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	return t
+}
+
+// begin starts tracking a call or a watch goroutine. It returns a context derived from ctx that is
+// also done when the transport is closed, and the function that ends the tracking, which must be called
+// when the call returns or the watch goroutine exits. It fails with ErrTransportClosed after close.
+func (t *tracker) begin(ctx context.Context) (context.Context, func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, nil, ErrTransportClosed
+	}
+	t.wg.Add(1)
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(t.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		t.wg.Done()
+	}, nil
+}
+
+// close cancels the tracked calls and watch goroutines, and waits until they end or the context is done.
+func (t *tracker) close(ctx context.Context) error {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+	t.cancel()
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
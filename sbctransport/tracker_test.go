@@ -0,0 +1,28 @@
+package sbctransport_test
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// checkNoTransportGoroutines fails the test if goroutines of the NATS or Consul transport are still running,
+// like goleak does for all goroutines. The goroutines get some time to exit.
+func checkNoTransportGoroutines(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		buf := make([]byte, 1<<20)
+		stacks := buf[:runtime.Stack(buf, true)]
+		leaked := bytes.Contains(stacks, []byte("sbctransport.NatsTransport.")) ||
+			bytes.Contains(stacks, []byte("sbctransport.ConsulTransport."))
+		if !leaked {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("transport goroutines left running:\n%s", stacks)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -3,22 +3,24 @@ package sbc
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/Autodoc-Technology/streaming-based-config/sbcencoder"
 )
 
 // Subscriber is a generic type that represents a subscriber for receiving updates from transport.
-// It holds a reference to the transport, subscription, and subscriber options.
+// It holds a reference to the transport, the key builder, and subscriber options, and does not keep
+// the subscriptions it starts, so it can be shared to start several of them.
 //
 // Use the Subscribe() method to start a new subscription by creating a new subscription instance and starting it.
 // The subscription is started by calling the start() method of the subscription instance.
 //
-// Use the Unsubscribe() method to stop the subscription by calling the stop() method of the subscription instance.
+// Use the Unsubscribe() or Close() method of the subscription to stop it.
 type Subscriber[T any] struct {
-	transport    Transport
-	subscription *Subscription[T]
-	keyBuilder   KeyBuilder[T]
-	opts         subscriberOpts
+	transport  Transport
+	keyBuilder KeyBuilder[T]
+	opts       subscriberOpts
 }
 
 // NewSubscriber creates a new subscriber
@@ -43,27 +45,70 @@ func NewSubscriber[T any](transport Transport, kb KeyBuilder[T], opts ...Subscri
 // If the subscription is not successfully started or the context is already done,
 // the returned subscription instance will be nil.
 func (s *Subscriber[T]) Subscribe(ctx context.Context) (*Subscription[T], error) {
-	sub, err := NewSubscription[T](s.transport, s.opts.encoder, s.keyBuilder).start(ctx)
+	sub, err := newSubscription(s.transport, s.keyBuilder, s.opts).start(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start subscription: %w", err)
 	}
-	s.subscription = sub
 	return sub, nil
 }
 
+// SubscribeWithParams starts a new subscription for the instance described by the params, see Subscribe.
+//
+// The params are stored in the subscription context with ContextWithKeyParams, so a ContextKeyBuilder,
+// like sbc.KeyBuilderContextFunc or sbckey.TemplateKeyBuilder, can build a key per instance, e.g.
+// "limits/tenant-42" and "limits/tenant-7" from the same Subscriber.
+func (s *Subscriber[T]) SubscribeWithParams(ctx context.Context, params KeyParams) (*Subscription[T], error) {
+	return s.Subscribe(ContextWithKeyParams(ctx, params))
+}
+
+// SubscribeAsync starts a new subscription without waiting for its initial value, which is loaded in
+// the background: the subscription gets it from the transport, retrying every retry interval,
+// see WithRetryInterval, or from the first update of its keys, e.g. when the key is created later.
+// When the first update is of a lower-priority key, the value of a higher-priority key that exists is used instead.
+// Until then, Get returns the zero value of T. Use WaitReady to wait for the initial value, or Ready
+// to report it, e.g. in a readiness probe. It fails only when the key cannot be built.
+func (s *Subscriber[T]) SubscribeAsync(ctx context.Context) (*Subscription[T], error) {
+	sub, err := newSubscription(s.transport, s.keyBuilder, s.opts).startAsync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// Close closes the transport of the Subscriber if it implements Closer. Close the subscriptions first,
+// and do not close a Subscriber whose transport is still used by another one.
+func (s *Subscriber[T]) Close(ctx context.Context) error {
+	c, ok := s.transport.(Closer)
+	if !ok {
+		return nil
+	}
+	if err := c.Close(ctx); err != nil {
+		return fmt.Errorf("failed to close transport: %w", err)
+	}
+	return nil
+}
+
 // SubscriberOpt is a function type used to configure a Subscriber instance.
 // It modifies the options of the subscriberOpts struct.
 type SubscriberOpt func(*subscriberOpts)
 
 // subscriberOpts is a struct that holds the options of a Subscriber instance.
 type subscriberOpts struct {
-	encoder Encoder
+	encoder       Encoder
+	dedup         Dedup
+	logger        *slog.Logger
+	immutable     bool
+	atomicHolder  bool
+	retryInterval time.Duration
 }
 
 // NewDefaultSubscriberOpts creates a new subscriber options with the default values
 func newDefaultSubscriberOpts() subscriberOpts {
 	return subscriberOpts{
-		encoder: sbcencoder.NewJsonEncoder(),
+		encoder:       sbcencoder.NewJsonEncoder(),
+		dedup:         DedupBytes,
+		logger:        slog.Default(),
+		retryInterval: time.Second,
 	}
 }
 
@@ -84,3 +129,47 @@ func WithEncoder(encoder Encoder) SubscriberOpt {
 		o.encoder = encoder
 	}
 }
+
+// WithDedup sets how the subscriptions of a Subscriber instance skip updates that do not change the value,
+// DedupBytes by default.
+func WithDedup(dedup Dedup) SubscriberOpt {
+	return func(o *subscriberOpts) {
+		o.dedup = dedup
+	}
+}
+
+// WithLogger sets the logger of a Subscriber instance, slog.Default() by default.
+func WithLogger(logger *slog.Logger) SubscriberOpt {
+	return func(o *subscriberOpts) {
+		o.logger = logger
+	}
+}
+
+// WithImmutableValues makes the subscriptions of a Subscriber instance return a deep copy of the value
+// from Get, GetWithVersion, GetUpdates, All and Changes, so a caller that modifies the maps, slices or
+// pointers of its copy does not corrupt the value of the other callers. The value is copied with its
+// Clone() T method if T has one, otherwise with reflection, which copies the exported fields of structs
+// and shares the unexported ones. The values passed to the OnChange handlers and the participants are
+// not copied and must not be modified.
+func WithImmutableValues() SubscriberOpt {
+	return func(o *subscriberOpts) {
+		o.immutable = true
+	}
+}
+
+// WithAtomicHolder makes the subscriptions of a Subscriber instance keep the value in an AtomicHolder
+// instead of a Holder, so Get does not take a lock. It pays off for the values that are read on hot paths
+// by many goroutines at once, see BenchmarkHolderGetValueParallel.
+func WithAtomicHolder() SubscriberOpt {
+	return func(o *subscriberOpts) {
+		o.atomicHolder = true
+	}
+}
+
+// WithRetryInterval sets how often the async subscriptions of a Subscriber instance retry loading
+// their initial value, see Subscriber.SubscribeAsync, one second by default.
+func WithRetryInterval(interval time.Duration) SubscriberOpt {
+	return func(o *subscriberOpts) {
+		o.retryInterval = interval
+	}
+}
@@ -0,0 +1,322 @@
+package sbc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Value int `json:"value"`
+}
+
+// waitFor waits until the condition is true, failing the test after a second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubscriberSubscribe(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	if confSubs.Key() != "config" || confSubs.Get().Value != 1 {
+		t.Errorf("unexpected subscription %s: %+v", confSubs.Key(), confSubs.Get())
+	}
+}
+
+func TestSubscriberSubscribeMissingKey(t *testing.T) {
+	transport := newMockTransport(nil)
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }))
+	if _, err := sub.Subscribe(context.Background()); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestSubscriberSubscribeWithParams(t *testing.T) {
+	transport := newMockTransport(map[string]string{
+		"limits/tenant-42": `{"value": 42}`,
+		"limits/tenant-7":  `{"value": 7}`,
+	})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderContextFunc[testConfig](func(ctx context.Context, _ testConfig) (string, error) {
+		return "limits/tenant-" + KeyParamsFromContext(ctx)["tenant"], nil
+	}))
+	for _, tenant := range []string{"42", "7"} {
+		confSubs, err := sub.SubscribeWithParams(context.Background(), KeyParams{"tenant": tenant})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if confSubs.Key() != "limits/tenant-"+tenant {
+			t.Errorf("unexpected key %s", confSubs.Key())
+		}
+		if expected := map[string]int{"42": 42, "7": 7}[tenant]; confSubs.Get().Value != expected {
+			t.Errorf("Expected %d, got %d", expected, confSubs.Get().Value)
+		}
+		confSubs.Unsubscribe()
+	}
+}
+
+// aliasKeys is a MultiKeyBuilder with fixed keys.
+type aliasKeys []string
+
+func (a aliasKeys) BuildKey(testConfig) string {
+	return a[0]
+}
+
+func (a aliasKeys) BuildKeys(context.Context, testConfig) ([]string, error) {
+	return a, nil
+}
+
+func TestSubscriberSubscribeWithAliases(t *testing.T) {
+	transport := newMockTransport(map[string]string{"legacy": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, aliasKeys{"new", "legacy"})
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	if confSubs.Key() != "legacy" || confSubs.Get().Value != 1 {
+		t.Fatalf("unexpected subscription %s: %+v", confSubs.Key(), confSubs.Get())
+	}
+	// the new key appears and takes over
+	transport.put("new", `{"value": 2}`)
+	waitFor(t, func() bool { return confSubs.Get().Value == 2 && confSubs.Key() == "new" })
+	// updates of the legacy key are ignored from now on
+	transport.put("legacy", `{"value": 3}`)
+	transport.put("new", `{"value": 4}`)
+	waitFor(t, func() bool { return confSubs.Get().Value == 4 })
+	if keys := confSubs.Keys(); len(keys) != 2 {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func TestSubscriptionLastUpdate(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	if upd := confSubs.LastUpdate(); upd.Key != "config" || upd.Revision != 1 || string(upd.Value) != `{"value": 1}` {
+		t.Errorf("unexpected update %+v", upd)
+	}
+	transport.put("config", `{"value": 2}`)
+	waitFor(t, func() bool { return confSubs.LastUpdate().Revision == 2 })
+	if confSubs.Get().Value != 2 {
+		t.Errorf("Expected 2, got %d", confSubs.Get().Value)
+	}
+	// the delete is reported, the last value is kept
+	transport.delete("config")
+	waitFor(t, func() bool { return confSubs.LastUpdate().Operation == OpDelete })
+	if confSubs.Get().Value != 2 {
+		t.Errorf("Expected 2, got %d", confSubs.Get().Value)
+	}
+}
+
+func TestSubscriberSubscribeWithAliasesFallbackOnDelete(t *testing.T) {
+	transport := newMockTransport(map[string]string{"new": `{"value": 2}`, "legacy": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, aliasKeys{"new", "legacy"})
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	if confSubs.Key() != "new" || confSubs.Get().Value != 2 {
+		t.Fatalf("unexpected subscription %s: %+v", confSubs.Key(), confSubs.Get())
+	}
+	transport.delete("new")
+	waitFor(t, func() bool { return confSubs.Key() == "legacy" && confSubs.Get().Value == 1 })
+}
+
+// headerEncoder is a HeaderDecoder that decodes the "Value" header.
+type headerEncoder struct {
+	Encoder
+}
+
+func (headerEncoder) DecodeWithHeaders(_ []byte, headers map[string]string, v any) error {
+	return json.Unmarshal([]byte(`{"value":`+headers["Value"]+`}`), v)
+}
+
+// headerTransport is an UpdateTransport whose updates carry a "Value" header.
+type headerTransport struct {
+	legacyTransport
+}
+
+func (headerTransport) CurrentUpdate(_ context.Context, key string) (Update, error) {
+	return Update{Key: key, Headers: map[string]string{"Value": "7"}}, nil
+}
+
+func (headerTransport) WatchUpdates(context.Context, string) (<-chan Update, error) {
+	return nil, nil
+}
+
+func TestSubscriptionDecodesWithHeaders(t *testing.T) {
+	sub := NewSubscriber[testConfig](headerTransport{}, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithEncoder(headerEncoder{}))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	if confSubs.Get().Value != 7 {
+		t.Errorf("Expected 7, got %d", confSubs.Get().Value)
+	}
+}
+
+func TestSubscriptionDecodeError(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	// the update that can not be decoded is recorded, the value is kept
+	transport.put("config", `{"value": "two"}`)
+	waitFor(t, func() bool { return confSubs.ApplyError() != nil })
+	var decodeErr *DecodeError
+	var typeErr *json.UnmarshalTypeError
+	if err := confSubs.ApplyError(); !errors.As(err, &decodeErr) || decodeErr.Version.Revision != 2 || !errors.As(err, &typeErr) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if confSubs.Get().Value != 1 {
+		t.Errorf("unexpected value %+v", confSubs.Get())
+	}
+	transport.put("config", `{"value": 3}`)
+	waitFor(t, func() bool { return confSubs.Get().Value == 3 })
+	if confSubs.ApplyError() != nil {
+		t.Errorf("unexpected error %v", confSubs.ApplyError())
+	}
+}
+
+// racingTransport is a mockTransport whose key changes right after its current value is read.
+type racingTransport struct {
+	*mockTransport
+	once sync.Once
+}
+
+func (r *racingTransport) CurrentUpdate(ctx context.Context, key string) (Update, error) {
+	upd, err := r.mockTransport.CurrentUpdate(ctx, key)
+	r.once.Do(func() { r.put(key, `{"value": 2}`) })
+	return upd, err
+}
+
+func TestSubscriberSubscribeChangeBeforeWatch(t *testing.T) {
+	transport := &racingTransport{mockTransport: newMockTransport(map[string]string{"config": `{"value": 1}`})}
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	// the change made between the read and the watch is received
+	waitFor(t, func() bool { return confSubs.Get().Value == 2 })
+	var mu sync.Mutex
+	var changes []int
+	confSubs.OnChange(func(_, new testConfig) error {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, new.Value)
+		return nil
+	})
+	// an update that is not newer than the value is skipped
+	transport.mu.Lock()
+	transport.notify(Update{Key: "config", Value: []byte(`{"value": 1}`), Revision: 1, Operation: OpPut})
+	transport.mu.Unlock()
+	transport.put("config", `{"value": 3}`)
+	waitFor(t, func() bool { return confSubs.Version().Revision == 3 })
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(changes, []int{3}) {
+		t.Errorf("Expected [3], got %v", changes)
+	}
+}
+
+func TestSubscriptionAll(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	done := make(chan []int)
+	go func() {
+		var values []int
+		for conf := range confSubs.All(context.Background()) {
+			values = append(values, conf.Value)
+			if conf.Value == 3 {
+				break
+			}
+		}
+		done <- values
+	}()
+	transport.put("config", `{"value": 2}`)
+	transport.put("config", `{"value": 3}`)
+	select {
+	case values := <-done:
+		// the loop may start after some of the updates, so only the last value is known
+		if len(values) == 0 || values[len(values)-1] != 3 {
+			t.Errorf("unexpected values %v", values)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the loop did not break")
+	}
+}
+
+func TestSubscriptionChanges(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan [2]int)
+	go func() {
+		defer close(changes)
+		for old, conf := range confSubs.Changes(ctx) {
+			changes <- [2]int{old.Value, conf.Value}
+		}
+	}()
+	// wait for the loop to start listening
+	time.Sleep(10 * time.Millisecond)
+	transport.put("config", `{"value": 2}`)
+	select {
+	case change := <-changes:
+		if change != [2]int{1, 2} {
+			t.Errorf("unexpected change %v", change)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no change received")
+	}
+	// the loop ends when the subscription is unsubscribed
+	confSubs.Unsubscribe()
+	select {
+	case _, ok := <-changes:
+		if ok {
+			t.Error("unexpected change")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the loop did not end")
+	}
+}
@@ -2,7 +2,12 @@ package sbc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"iter"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Subscription represents a Subscription to receive updates from a transport.
@@ -18,30 +23,112 @@ import (
 //
 // Use the getAndDecode() method to get and decode the initial value from the transport.
 //
-// Use the decode() method to decode the byte slice into type T.
+// Use the LastUpdate() method to get the metadata of the update the current value was received with.
 type Subscription[T any] struct {
-	transport  Transport
+	transport  UpdateTransport
 	encoder    Encoder
 	keyBuilder KeyBuilder[T]
+	keys       []string
+	active     atomic.Int32
+	lastUpdate atomic.Pointer[Update]
+	opts       subscriberOpts
+	handlers   changeHandlers[T]
+	parts      participants[T]
+	applyErr   atomic.Pointer[error]
+	// registry is the registry the subscription is registered in, see Register
+	registry atomic.Pointer[Registry]
+	// applyMu serializes the applies of the update goroutine and the initial load of an async subscription
+	applyMu   sync.Mutex
+	ready     chan struct{}
+	readyOnce sync.Once
+	loadErr   atomic.Pointer[error]
+	termErr   atomic.Pointer[error]
+	// goroutines are the goroutines of the subscription, Close waits for them
+	goroutines goroutines
 
-	holder *Holder[T]
+	holder valueHolder[T]
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
 // NewSubscription creates a new subscription
 func NewSubscription[T any](transport Transport, encoder Encoder, kb KeyBuilder[T]) *Subscription[T] {
-	return &Subscription[T]{transport: transport, encoder: encoder, keyBuilder: kb}
+	return newSubscription(transport, kb, newDefaultSubscriberOpts().apply([]SubscriberOpt{WithEncoder(encoder)}))
+}
+
+// newSubscription creates a new subscription with the given subscriber options
+func newSubscription[T any](transport Transport, kb KeyBuilder[T], opts subscriberOpts) *Subscription[T] {
+	return &Subscription[T]{transport: AdaptTransport(transport), encoder: opts.encoder, keyBuilder: kb, opts: opts}
 }
 
 // Unsubscribe permanently stops the Subscription and cancel the context.
+// It does not wait for the goroutines of the subscription to exit, see Close.
 func (sub *Subscription[T]) Unsubscribe() {
 	sub.cancel()
 }
 
+// Close permanently stops the Subscription like Unsubscribe, and waits until all its goroutines exit:
+// the update goroutines, the GetUpdates goroutines, the OnChange handlers, and the watch goroutines
+// of the transport, whose channels are drained until the transport closes them.
+// It returns the terminal error of the subscription, see Err, or the context error if the goroutines
+// do not exit before the context is done. Once Close is called, no goroutine is started anymore:
+// GetUpdates returns a closed channel and the handlers with a timeout fail with ErrClosing.
+func (sub *Subscription[T]) Close(ctx context.Context) error {
+	sub.cancel()
+	if err := sub.goroutines.wait(ctx); err != nil {
+		return errors.Join(fmt.Errorf("failed to wait for the subscription goroutines: %w", err), sub.Err())
+	}
+	return sub.Err()
+}
+
+// Err returns the terminal error of the subscription: the updates of the transport ended while the
+// subscription was running, so its value does not change anymore, or an async subscription was stopped
+// before it got its initial value, see SubscribeAsync. It returns nil otherwise.
+func (sub *Subscription[T]) Err() error {
+	if err := sub.termErr.Load(); err != nil {
+		return *err
+	}
+	if !sub.Ready() && sub.ctx.Err() != nil && sub.loadErr.Load() != nil {
+		return sub.notReady(sub.ctx.Err())
+	}
+	return nil
+}
+
+// Key returns the currently active key, i.e. the key the current value was received from.
+// When the key builder resolves to several keys (see MultiKeyBuilder), it is the highest-priority key
+// that exists in the transport.
+func (sub *Subscription[T]) Key() string {
+	return sub.keys[sub.active.Load()]
+}
+
+// Keys returns all the keys the subscription watches, ordered by priority.
+func (sub *Subscription[T]) Keys() []string {
+	return append([]string(nil), sub.keys...)
+}
+
+// LastUpdate returns the update the current value was received with: its key, raw value, revision,
+// timestamp and transport headers. When the active key is deleted and no fallback key exists,
+// it is the delete update, while Get keeps returning the last value.
+func (sub *Subscription[T]) LastUpdate() Update {
+	return *sub.lastUpdate.Load()
+}
+
 // Get returns the current value of the subscription.
 func (sub *Subscription[T]) Get() T {
-	return sub.holder.GetValue()
+	return sub.view(sub.holder.GetValue())
+}
+
+// Version returns the version of the current value: its key, transport revision and content hash.
+func (sub *Subscription[T]) Version() Version {
+	_, version := sub.holder.getValueVersion()
+	return version
+}
+
+// GetWithVersion returns the current value of the subscription and its version, read atomically,
+// so the version always describes the returned value.
+func (sub *Subscription[T]) GetWithVersion() (T, Version) {
+	val, version := sub.holder.getValueVersion()
+	return sub.view(val), version
 }
 
 // GetUpdates returns a receive-only channel that sends updates of type T.
@@ -49,55 +136,501 @@ func (sub *Subscription[T]) Get() T {
 // It continuously sends the current value of the subscription holder to the channel.
 // If the context is done, it stops sending updates and closes the channel.
 func (sub *Subscription[T]) GetUpdates() <-chan T {
-	return sub.holder.Updates(sub.ctx)
+	out := make(chan T)
+	_, seq := sub.holder.current()
+	started := sub.goroutines.Go(func() {
+		defer close(out)
+		for val := range valuesAfter(sub.ctx, sub.holder, seq) {
+			select {
+			case out <- sub.view(val):
+			case <-sub.ctx.Done():
+				return
+			}
+		}
+	})
+	if !started {
+		// the subscription is closing
+		close(out)
+	}
+	return out
+}
+
+// view returns the value as it is handed out to the callers: a deep copy in the immutable mode,
+// see WithImmutableValues, the value itself otherwise.
+func (sub *Subscription[T]) view(val T) T {
+	if sub.opts.immutable {
+		return cloneValue(val)
+	}
+	return val
+}
+
+// OnChange registers a handler that is called with the previous and the new value after every change
+// of the subscription value, and returns the function that removes it. The handlers are called one by one
+// on the update goroutine, in ascending order of priority and then in the order of registration,
+// see WithHandlerPriority. A panic or a timeout, see WithHandlerTimeout, is an error of the handler,
+// and the ErrorPolicy of the handler defines whether the error is only logged or the change is rolled back.
+func (sub *Subscription[T]) OnChange(fn func(old, new T) error, opts ...OnChangeOpt) (remove func()) {
+	return sub.handlers.add(fn, opts)
+}
+
+// AddParticipant registers a participant of the two-phase apply of new values and returns the function
+// that removes it, see Participant. The participants are called in the order of registration: they prepare
+// the value before the OnChange handlers are called, and commit it after them.
+func (sub *Subscription[T]) AddParticipant(p Participant[T]) (remove func()) {
+	return sub.parts.add(p)
+}
+
+// ApplyError returns the error of the last update that was not applied, or nil if the last update was applied.
+// It is a *DecodeError when the encoder rejected the value, e.g. a validation or signature error,
+// and a *PrepareError when a participant rejected it.
+func (sub *Subscription[T]) ApplyError() error {
+	if err := sub.applyErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Ready reports whether the subscription has its initial value, see Subscriber.SubscribeAsync.
+// A subscription started by Subscribe is always ready.
+func (sub *Subscription[T]) Ready() bool {
+	select {
+	case <-sub.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitReady waits until the subscription has its initial value, see Subscriber.SubscribeAsync.
+// It fails with ErrNotReady when the context is done or the subscription is unsubscribed first,
+// the error includes the last error of loading the initial value.
+func (sub *Subscription[T]) WaitReady(ctx context.Context) error {
+	if sub.Ready() {
+		return nil
+	}
+	select {
+	case <-sub.ready:
+		return nil
+	case <-ctx.Done():
+		return sub.notReady(ctx.Err())
+	case <-sub.ctx.Done():
+		return sub.notReady(sub.ctx.Err())
+	}
+}
+
+// notReady returns the ErrNotReady error with the cause and the last error of loading the initial value.
+func (sub *Subscription[T]) notReady(cause error) error {
+	if err := sub.loadErr.Load(); err != nil {
+		return fmt.Errorf("%w: %w, last error: %w", ErrNotReady, cause, *err)
+	}
+	return fmt.Errorf("%w: %w", ErrNotReady, cause)
+}
+
+// markReady marks the subscription ready.
+func (sub *Subscription[T]) markReady() {
+	sub.readyOnce.Do(func() {
+		sub.loadErr.Store(nil)
+		close(sub.ready)
+	})
+}
+
+// setLoadErr records an error of loading the initial value.
+func (sub *Subscription[T]) setLoadErr(err error) {
+	sub.loadErr.Store(&err)
+}
+
+// All returns an iterator over the values of the subscription: the current value first, then every new value.
+// The iteration stops when the loop breaks, the context is done or the subscription is unsubscribed,
+// and nothing is left running afterwards. A slow loop skips the intermediate values and gets the latest one.
+//
+//	for conf := range sub.All(ctx) {
+//		reconfigure(conf)
+//	}
+func (sub *Subscription[T]) All(ctx context.Context) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ctx, cancel := sub.iterContext(ctx)
+		defer cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		val, seq := sub.holder.current()
+		if !yield(sub.view(val)) {
+			return
+		}
+		for val := range valuesAfter(ctx, sub.holder, seq) {
+			if !yield(sub.view(val)) {
+				return
+			}
+		}
+	}
+}
+
+// Changes returns an iterator over the changes of the subscription value, yielding the old and the new value.
+// The iteration stops when the loop breaks, the context is done or the subscription is unsubscribed,
+// and nothing is left running afterwards. A slow loop skips the intermediate values.
+//
+//	for old, conf := range sub.Changes(ctx) {
+//		log.Printf("config changed from %v to %v", old, conf)
+//	}
+func (sub *Subscription[T]) Changes(ctx context.Context) iter.Seq2[T, T] {
+	return func(yield func(T, T) bool) {
+		ctx, cancel := sub.iterContext(ctx)
+		defer cancel()
+		old, seq := sub.holder.current()
+		for val := range valuesAfter(ctx, sub.holder, seq) {
+			if !yield(sub.view(old), sub.view(val)) {
+				return
+			}
+			old = val
+		}
+	}
+}
+
+// iterContext returns a context that is done when either the given context or the subscription context is done.
+func (sub *Subscription[T]) iterContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return mergeContext(ctx, sub.ctx)
+}
+
+// mergeContext returns a context that is done when either the given context or the other context is done.
+func mergeContext(ctx, other context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(other, cancel)
+	if other.Err() != nil {
+		// AfterFunc calls cancel on its own goroutine, make the merged context done right away
+		cancel()
+	}
+	return ctx, func() {
+		stop()
+		cancel()
+	}
 }
 
 // start starts the Subscription and receives updates from the transport.
 func (sub *Subscription[T]) start(ctx context.Context) (*Subscription[T], error) {
-	sub.ctx, sub.cancel = context.WithCancel(ctx)
-	var defT T
-	key := sub.keyBuilder.BuildKey(defT)
-	// get the initial value from the transport
-	val, err := sub.getAndDecode(sub.ctx, key)
+	if err := sub.init(ctx); err != nil {
+		sub.cancel()
+		return nil, err
+	}
+	// watch before getting the initial value, so a change made in between is not lost,
+	// the updates the initial value already includes are skipped by revision, see handle
+	updates, err := sub.watch(sub.ctx)
+	if err != nil {
+		sub.cancel()
+		return nil, fmt.Errorf("failed to get updates from transport: %w", err)
+	}
+	// get the initial value from the highest-priority key that exists
+	idx, val, upd, err := sub.getFirst(sub.ctx, 0)
 	if err != nil {
+		sub.cancel()
 		return nil, fmt.Errorf("failed to get and decode initial value: %w", err)
 	}
-	sub.holder = NewHolder(*val)
-	// iterate the transport updates and update the holder
-	updates, err := sub.transport.Updates(sub.ctx, key)
+	sub.active.Store(int32(idx))
+	sub.lastUpdate.Store(&upd)
+	sub.holder = sub.newHolder(val, newVersion(upd))
+	sub.markReady()
+	// iterate the transport updates of all keys and update the holder
+	sub.goroutines.Go(func() { sub.run(updates) })
+	return sub, nil
+}
+
+// startAsync starts the Subscription without waiting for the initial value, which is loaded in the background.
+// Until it is loaded, the subscription holds the zero value of T and is not ready.
+func (sub *Subscription[T]) startAsync(ctx context.Context) (*Subscription[T], error) {
+	if err := sub.init(ctx); err != nil {
+		sub.cancel()
+		return nil, err
+	}
+	var defT T
+	sub.lastUpdate.Store(&Update{})
+	sub.holder = sub.newHolder(defT, Version{})
+	sub.goroutines.Go(sub.load)
+	return sub, nil
+}
+
+// init creates the context of the subscription and builds its keys.
+func (sub *Subscription[T]) init(ctx context.Context) error {
+	sub.ctx, sub.cancel = context.WithCancel(ctx)
+	sub.ready = make(chan struct{})
+	sub.handlers.spawn = sub.goroutines.Go
+	var defT T
+	keys, err := BuildKeys(sub.ctx, sub.keyBuilder, defT)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get updates from transport: %w", err)
+		return fmt.Errorf("failed to build key: %w", err)
 	}
-	go func() {
-		for upd := range updates {
-			val, err := sub.decode(upd)
+	sub.keys = keys
+	return nil
+}
+
+// load watches the keys and gets the initial value of an async subscription, retrying until the subscription
+// is ready or its context is done. A key that is created later is received by the watch.
+func (sub *Subscription[T]) load() {
+	watching := false
+	for {
+		if !watching {
+			updates, err := sub.watch(sub.ctx)
 			if err != nil {
-				continue
+				sub.setLoadErr(fmt.Errorf("failed to get updates from transport: %w", err))
+			} else {
+				watching = true
+				sub.goroutines.Go(func() { sub.run(updates) })
 			}
-			sub.holder.setValue(val)
 		}
-	}()
-	return sub, nil
+		if !sub.Ready() {
+			idx, val, upd, err := sub.getFirst(sub.ctx, 0)
+			if err != nil {
+				sub.setLoadErr(fmt.Errorf("failed to get and decode initial value: %w", err))
+			} else {
+				sub.applyInitial(idx, val, upd)
+			}
+		}
+		if watching && sub.Ready() {
+			return
+		}
+		select {
+		case <-sub.ctx.Done():
+			return
+		case <-time.After(sub.opts.retryInterval):
+		}
+	}
+}
+
+// applyInitial applies the initial value of an async subscription, unless an update was applied first.
+func (sub *Subscription[T]) applyInitial(idx int, val T, upd Update) {
+	sub.applyMu.Lock()
+	defer sub.applyMu.Unlock()
+	if !sub.Ready() {
+		sub.apply(idx, val, upd)
+	}
+}
+
+// ErrUpdatesClosed is an error that is returned when the transport stops sending the updates of a running
+// subscription, so its value does not change anymore.
+var ErrUpdatesClosed = errors.New("transport updates closed")
+
+// run applies the updates of the keys until the updates are closed. A nil channel means that the transport
+// has no updates for the keys.
+func (sub *Subscription[T]) run(updates <-chan keyedUpdate) {
+	if updates == nil {
+		return
+	}
+	for ku := range updates {
+		sub.handle(ku)
+	}
+	if sub.ctx.Err() == nil {
+		err := ErrUpdatesClosed
+		sub.termErr.Store(&err)
+		sub.opts.logger.Error("config updates stopped", "keys", sub.keys, "error", err)
+	}
+}
+
+// handle applies an update of a key.
+func (sub *Subscription[T]) handle(ku keyedUpdate) {
+	sub.applyMu.Lock()
+	defer sub.applyMu.Unlock()
+	ready := sub.Ready()
+	active := int(sub.active.Load())
+	// updates of keys with a lower priority than the active one are ignored,
+	// until the subscription is ready any key is welcome
+	if ready && ku.index > active {
+		return
+	}
+	// the updates of the active key that are not newer than its value were received before it was read
+	if ready && ku.index == active && ku.update.Revision != 0 && ku.update.Revision <= sub.lastUpdate.Load().Revision {
+		return
+	}
+	if !ready && ku.index > 0 && ku.update.Operation != OpDelete {
+		// the initial value must come from the highest-priority key that exists, not from the first update
+		if idx, val, upd, err := sub.getFirst(sub.ctx, 0); err == nil && idx < ku.index {
+			sub.apply(idx, val, upd)
+			return
+		}
+	}
+	if ku.update.Operation == OpDelete {
+		if ready && ku.index == active {
+			sub.fallback(active, ku.update)
+		}
+		return
+	}
+	val, err := decodeUpdate[T](sub.encoder, ku.update)
+	if err != nil {
+		version := newVersion(ku.update)
+		err = &DecodeError{Version: version, Err: err}
+		sub.applyErr.Store(&err)
+		sub.opts.logger.Error("config update not decoded", "key", ku.update.Key, "version", version.String(), "error", err)
+		return
+	}
+	sub.apply(ku.index, val, ku.update)
 }
 
-// getAndDecode gets and decodes the initial value from the transport.
-func (sub *Subscription[T]) getAndDecode(ctx context.Context, key string) (*T, error) {
-	b, err := sub.transport.Current(ctx, key)
+// newHolder creates the holder of the subscription value, see WithAtomicHolder.
+func (sub *Subscription[T]) newHolder(val T, version Version) valueHolder[T] {
+	if sub.opts.atomicHolder {
+		h := NewAtomicHolder(val)
+		h.setVersion(version)
+		return h
+	}
+	h := NewHolder(val)
+	h.setVersion(version)
+	return h
+}
+
+// apply makes the key with the given index active and stores the value received with the update.
+// If the update does not change the value, see Dedup, only the version is stored.
+// If a participant rejects the value, nothing changes and the error is recorded, see ApplyError.
+// If a handler rolls the change back, the participants are aborted instead of committed, and an async
+// subscription that was not ready stays not ready.
+func (sub *Subscription[T]) apply(index int, val T, upd Update) {
+	version := newVersion(upd)
+	cur, curVersion := sub.holder.getValueVersion()
+	if isNoop(sub.opts.dedup, cur, curVersion, val, version) {
+		sub.active.Store(int32(index))
+		sub.lastUpdate.Store(&upd)
+		sub.store(func() { sub.holder.setVersion(version) })
+		sub.applyErr.Store(nil)
+		sub.markReady()
+		return
+	}
+	commit, abort, err := sub.parts.prepare(sub.ctx, cur, val)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current value: %w", err)
+		err = &PrepareError{Version: version, Err: err}
+		sub.applyErr.Store(&err)
+		sub.opts.logger.Error("config change rejected", "key", upd.Key, "version", version.String(), "error", err)
+		return
+	}
+	sub.applyErr.Store(nil)
+	prevActive, prevUpdate := sub.active.Load(), sub.lastUpdate.Load()
+	sub.active.Store(int32(index))
+	sub.lastUpdate.Store(&upd)
+	sub.store(func() { sub.holder.setValueVersion(val, version) })
+	rollback := sub.handlers.notify(cur, val, func(err error) {
+		sub.opts.logger.Error("config change handler failed", "key", upd.Key, "version", version.String(), "error", err)
+	})
+	if rollback {
+		// the participants have not committed the value yet, so they are aborted
+		sub.active.Store(prevActive)
+		sub.lastUpdate.Store(prevUpdate)
+		sub.store(func() { sub.holder.setValueVersion(cur, curVersion) })
+		if err := abort(); err != nil {
+			sub.opts.logger.Error("config change abort failed", "key", upd.Key, "version", version.String(), "error", err)
+		}
+		return
+	}
+	// the subscription is ready only with a value that the handlers and the participants accepted
+	sub.markReady()
+	if err := commit(); err != nil {
+		sub.opts.logger.Error("config change commit failed", "key", upd.Key, "version", version.String(), "error", err)
+	}
+}
+
+// store stores a change of the holder. When the subscription is registered in a Registry, the change is
+// stored under the registry lock, so a Snapshot never sees it half-way.
+func (sub *Subscription[T]) store(set func()) {
+	if r := sub.registry.Load(); r != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
 	}
-	val, err := sub.decode(b)
+	set()
+}
+
+// fallback switches to the value of the next key that exists after the active key was deleted.
+// If there is no such key, the last value is kept and only the delete update is recorded.
+func (sub *Subscription[T]) fallback(active int, deleted Update) {
+	idx, val, upd, err := sub.getFirst(sub.ctx, active+1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode value: %w", err)
+		sub.lastUpdate.Store(&deleted)
+		return
+	}
+	sub.apply(idx, val, upd)
+}
+
+// errNoKeyLeft is an error that is returned when there is no key left to get the value from.
+var errNoKeyLeft = errors.New("no key left")
+
+// keyedUpdate is an update received from the transport for the key with the given index.
+type keyedUpdate struct {
+	index  int
+	update Update
+}
+
+// getFirst gets and decodes the current value of the first key, starting from the given index,
+// that exists in the transport.
+func (sub *Subscription[T]) getFirst(ctx context.Context, from int) (int, T, Update, error) {
+	var errs []error
+	for i := from; i < len(sub.keys); i++ {
+		val, upd, err := sub.getAndDecode(ctx, sub.keys[i])
+		if err == nil {
+			return i, val, upd, nil
+		}
+		errs = append(errs, fmt.Errorf("key '%s': %w", sub.keys[i], err))
+	}
+	if len(errs) == 0 {
+		errs = append(errs, errNoKeyLeft)
+	}
+	var defT T
+	return 0, defT, Update{}, errors.Join(errs...)
+}
+
+// watch subscribes to the updates of all keys and merges them into one channel.
+// The channel is closed when the updates of all keys are closed, it is nil when the transport
+// has no updates for any of the keys. When the context is done, the updates of the keys are drained
+// until the transport closes them, so the watch goroutines of the transport can exit.
+// If the watch of a key fails, the watches of the keys already started are stopped.
+func (sub *Subscription[T]) watch(ctx context.Context) (<-chan keyedUpdate, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan keyedUpdate)
+	var wg sync.WaitGroup
+	watched := 0
+	for i, key := range sub.keys {
+		updates, err := sub.transport.WatchUpdates(ctx, key)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("key '%s': %w", key, err)
+		}
+		if updates == nil {
+			// the transport has no updates for the key
+			continue
+		}
+		watched++
+		wg.Add(1)
+		// the goroutine is refused only when the subscription is closing and nobody reads the updates anymore
+		started := sub.goroutines.Go(func() {
+			defer wg.Done()
+			for upd := range updates {
+				select {
+				case out <- keyedUpdate{index: i, update: upd}:
+				case <-ctx.Done():
+					for range updates {
+					}
+					return
+				}
+			}
+		})
+		if !started {
+			wg.Done()
+		}
 	}
-	return &val, nil
+	if watched == 0 {
+		cancel()
+		return nil, nil
+	}
+	sub.goroutines.Go(func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	})
+	return out, nil
 }
 
-// decode decodes the byte slice into type T.
-func (sub *Subscription[T]) decode(b []byte) (T, error) {
-	var t T
-	if err := sub.encoder.Decode(b, &t); err != nil {
-		return t, err
+// getAndDecode gets and decodes the current value of the key from the transport.
+func (sub *Subscription[T]) getAndDecode(ctx context.Context, key string) (T, Update, error) {
+	upd, err := sub.transport.CurrentUpdate(ctx, key)
+	if err != nil {
+		var defT T
+		return defT, Update{}, fmt.Errorf("failed to get current value: %w", err)
+	}
+	val, err := decodeUpdate[T](sub.encoder, upd)
+	if err != nil {
+		return val, Update{}, fmt.Errorf("failed to decode value: %w", err)
 	}
-	return t, nil
+	return val, upd, nil
 }
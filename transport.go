@@ -2,6 +2,8 @@ package sbc
 
 import (
 	"context"
+	"fmt"
+	"time"
 )
 
 // Transport defines an interface for accessing current data and subscribing to updates using a key and context.
@@ -15,3 +17,160 @@ type Transport interface {
 	// It stops and returns an error if the context is canceled.
 	Updates(ctx context.Context, key string) (<-chan []byte, error)
 }
+
+// Operation is the kind of change carried by an Update.
+type Operation int
+
+const (
+	// OpPut means that the value of the key was created or updated.
+	OpPut Operation = iota
+
+	// OpDelete means that the key was deleted.
+	OpDelete
+)
+
+// String returns the string representation of the Operation.
+func (o Operation) String() string {
+	switch o {
+	case OpPut:
+		return "put"
+	case OpDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("Operation(%d)", int(o))
+	}
+}
+
+// Update is a change of a single key received from a transport, together with its metadata.
+type Update struct {
+
+	// Key is the key that changed.
+	Key string
+
+	// Value is the new value of the key, empty for deletes.
+	Value []byte
+
+	// Revision is the transport revision of the value, like the NATS KV revision or the Consul ModifyIndex.
+	// Zero means that the transport has no revisions.
+	Revision uint64
+
+	// Timestamp is the modification time of the value if the transport knows it,
+	// otherwise the time the update was received.
+	Timestamp time.Time
+
+	// Operation is the kind of change.
+	Operation Operation
+
+	// Headers are transport-specific metadata, like the Consul flags or session of the value.
+	Headers map[string]string
+}
+
+// UpdateTransport is the evolved transport contract, whose updates carry the key, the value and the metadata
+// of every change, deletes included. Transports implement it next to Transport, and AdaptTransport turns
+// any Transport into an UpdateTransport, so existing transports keep working unchanged.
+type UpdateTransport interface {
+
+	// CurrentUpdate retrieves the current value associated with the specified key together with its metadata.
+	CurrentUpdate(ctx context.Context, key string) (Update, error)
+
+	// WatchUpdates returns a channel that streams the updates of the specified key, deletes included.
+	// It stops and closes the channel when the context is canceled.
+	WatchUpdates(ctx context.Context, key string) (<-chan Update, error)
+}
+
+// AdaptTransport returns the transport itself if it implements UpdateTransport, and an adapter otherwise.
+// The adapter wraps the raw values into updates without a revision, timestamped with the time of receipt.
+func AdaptTransport(t Transport) UpdateTransport {
+	if ut, ok := t.(UpdateTransport); ok {
+		return ut
+	}
+	return transportAdapter{t: t}
+}
+
+// transportAdapter adapts a Transport to the UpdateTransport contract.
+type transportAdapter struct {
+	t Transport
+}
+
+// CurrentUpdate retrieves the current value from the adapted transport.
+func (a transportAdapter) CurrentUpdate(ctx context.Context, key string) (Update, error) {
+	b, err := a.t.Current(ctx, key)
+	if err != nil {
+		return Update{}, err
+	}
+	return Update{Key: key, Value: b, Timestamp: time.Now(), Operation: OpPut}, nil
+}
+
+// WatchUpdates wraps the values streamed by the adapted transport into updates.
+// A nil channel of the adapted transport, meaning no updates, is returned as is.
+func (a transportAdapter) WatchUpdates(ctx context.Context, key string) (<-chan Update, error) {
+	updates, err := a.t.Updates(ctx, key)
+	if err != nil || updates == nil {
+		return nil, err
+	}
+	ch := make(chan Update)
+	go func() {
+		defer close(ch)
+		for b := range updates {
+			select {
+			case ch <- Update{Key: key, Value: b, Timestamp: time.Now(), Operation: OpPut}:
+			case <-ctx.Done():
+				// drain the updates, so the goroutine of the transport is not blocked on a send
+				for range updates {
+				}
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// PrefixTransport is an optional interface of a Transport that can watch all keys under a common prefix.
+// It is required by Subscriber.SubscribePrefix, which starts watching the prefix before it reads the current
+// values, so no change between the two is lost, and skips the changes the current values already include
+// by their revisions.
+type PrefixTransport interface {
+
+	// CurrentPrefix retrieves the current values of all keys starting with the prefix with their metadata,
+	// indexed by the full key.
+	CurrentPrefix(ctx context.Context, prefix string) (map[string]Update, error)
+
+	// UpdatesPrefix returns a channel that streams the changes, deletes included, of all keys starting with
+	// the prefix, made after it returns. It stops and closes the channel when the context is canceled.
+	UpdatesPrefix(ctx context.Context, prefix string) (<-chan Update, error)
+}
+
+// KeyInfo describes a key stored in a transport.
+type KeyInfo struct {
+
+	// Key is the full key.
+	Key string
+
+	// Revision is the transport revision of the current value, like the NATS KV revision or the Consul
+	// ModifyIndex. Zero means that the transport has no revisions.
+	Revision uint64
+}
+
+// Lister is an optional interface of a Transport that can enumerate the stored keys, for tooling, audits
+// and prefix subscriptions. Check for it with a type assertion:
+//
+//	if lister, ok := transport.(sbc.Lister); ok {
+//		keys, err := lister.List(ctx, "tenants/")
+//	}
+type Lister interface {
+
+	// List returns the keys starting with the prefix and their revisions, ordered by key.
+	// An empty prefix lists all keys.
+	List(ctx context.Context, prefix string) ([]KeyInfo, error)
+}
+
+// Closer is an optional interface of a Transport that owns background goroutines or connections, like
+// the NATS and Consul transports, whose Close stops all their watches. Subscriber.Close calls it.
+// A single watch does not need it: it stops when the context of the watch is done and closes its channel,
+// which Subscription.Close waits for.
+type Closer interface {
+
+	// Close releases the resources of the transport and waits until its goroutines exit,
+	// or until the context is done.
+	Close(ctx context.Context) error
+}
@@ -0,0 +1,65 @@
+package sbc
+
+import (
+	"context"
+	"testing"
+)
+
+// legacyTransport is a Transport that streams the values sent to its channel.
+type legacyTransport struct {
+	ch chan []byte
+}
+
+func (l legacyTransport) Current(context.Context, string) ([]byte, error) {
+	return []byte("current"), nil
+}
+
+func (l legacyTransport) Updates(context.Context, string) (<-chan []byte, error) {
+	if l.ch == nil {
+		return nil, nil
+	}
+	return l.ch, nil
+}
+
+func TestAdaptTransportReturnsUpdateTransport(t *testing.T) {
+	transport := newMockTransport(nil)
+	if AdaptTransport(transport) != UpdateTransport(transport) {
+		t.Error("Expected the transport itself")
+	}
+}
+
+func TestAdaptTransportWrapsLegacyTransport(t *testing.T) {
+	ch := make(chan []byte)
+	ut := AdaptTransport(legacyTransport{ch: ch})
+	upd, err := ut.CurrentUpdate(context.Background(), "key")
+	if err != nil || upd.Key != "key" || string(upd.Value) != "current" || upd.Operation != OpPut || upd.Timestamp.IsZero() {
+		t.Errorf("unexpected update %+v, %v", upd, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := ut.WatchUpdates(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch <- []byte("next")
+	if upd := <-updates; upd.Key != "key" || string(upd.Value) != "next" {
+		t.Errorf("unexpected update %+v", upd)
+	}
+	close(ch)
+	if _, ok := <-updates; ok {
+		t.Error("Expected closed channel")
+	}
+}
+
+func TestAdaptTransportWithoutUpdates(t *testing.T) {
+	updates, err := AdaptTransport(legacyTransport{}).WatchUpdates(context.Background(), "key")
+	if updates != nil || err != nil {
+		t.Errorf("Expected nil channel, got %v, %v", updates, err)
+	}
+}
+
+func TestOperationString(t *testing.T) {
+	if OpPut.String() != "put" || OpDelete.String() != "delete" || Operation(5).String() != "Operation(5)" {
+		t.Fail()
+	}
+}
@@ -0,0 +1,46 @@
+package sbc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Version identifies the version of a config value a subscription holds.
+type Version struct {
+
+	// Key is the key the value was received from.
+	Key string
+
+	// Revision is the transport revision of the value, like the NATS KV revision or the Consul ModifyIndex.
+	// Zero means that the transport has no revisions.
+	Revision uint64
+
+	// Hash is the hex-encoded SHA-256 hash of the raw value.
+	Hash string
+}
+
+// newVersion creates the Version of the value received with the update.
+func newVersion(upd Update) Version {
+	sum := sha256.Sum256(upd.Value)
+	return Version{Key: upd.Key, Revision: upd.Revision, Hash: hex.EncodeToString(sum[:])}
+}
+
+// String returns the string representation of the Version, suitable for logs, e.g. "config@12#3a7bd3e2360a3d29".
+func (v Version) String() string {
+	return v.Key + "@" + strconv.FormatUint(v.Revision, 10) + "#" + v.shortHash()
+}
+
+// ETag returns a strong HTTP entity tag of the value. It depends on the content only,
+// so the same value stored under another revision gets the same ETag.
+func (v Version) ETag() string {
+	return strconv.Quote(v.Hash)
+}
+
+// shortHash returns the first 16 characters of the hash.
+func (v Version) shortHash() string {
+	if len(v.Hash) > 16 {
+		return v.Hash[:16]
+	}
+	return v.Hash
+}
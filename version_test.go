@@ -0,0 +1,61 @@
+package sbc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSubscriptionVersion(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	first := confSubs.Version()
+	if first.Key != "config" || first.Revision != 1 || len(first.Hash) != 64 {
+		t.Fatalf("unexpected version %+v", first)
+	}
+	transport.put("config", `{"value": 2}`)
+	waitFor(t, func() bool { return confSubs.Version().Revision == 2 })
+	val, version := confSubs.GetWithVersion()
+	if val.Value != 2 || version.Hash == first.Hash {
+		t.Errorf("unexpected value %+v with version %+v", val, version)
+	}
+	// the same content under a new revision keeps the ETag
+	transport.put("config", `{"value": 2}`)
+	waitFor(t, func() bool { return confSubs.Version().Revision == 3 })
+	if confSubs.Version().ETag() != version.ETag() {
+		t.Errorf("Expected ETag %s, got %s", version.ETag(), confSubs.Version().ETag())
+	}
+}
+
+func TestVersionString(t *testing.T) {
+	v := newVersion(Update{Key: "config", Revision: 12, Value: []byte("foobar")})
+	if s := v.String(); s != "config@12#c3ab8ff13720e8ad" {
+		t.Errorf("unexpected string %s", s)
+	}
+	if etag := v.ETag(); etag != `"`+v.Hash+`"` {
+		t.Errorf("unexpected ETag %s", etag)
+	}
+}
+
+func TestSubscriptionVersionSemanticDedup(t *testing.T) {
+	transport := newMockTransport(map[string]string{"config": `{"value": 1}`})
+	sub := NewSubscriber[testConfig](transport, KeyBuilderFunc[testConfig](func(testConfig) string { return "config" }),
+		WithDedup(DedupSemantic))
+	confSubs, err := sub.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer confSubs.Unsubscribe()
+	// a reformatted value is skipped, the version still follows the transport
+	transport.put("config", `{"value":1}`)
+	waitFor(t, func() bool { return confSubs.Version().Revision == 2 })
+	if upd := confSubs.LastUpdate(); string(upd.Value) != `{"value":1}` || confSubs.Get().Value != 1 {
+		t.Errorf("unexpected update %+v", upd)
+	}
+	transport.put("config", `{"value":2}`)
+	waitFor(t, func() bool { return confSubs.Get().Value == 2 })
+}